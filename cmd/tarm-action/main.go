@@ -1,28 +1,105 @@
 package main
 
 import (
+	"cmp"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/kzmshx/tarm/internal/formatter"
-	"github.com/kzmshx/tarm/internal/git"
-	"github.com/kzmshx/tarm/internal/tarm"
+	"github.com/kzmshx/tarm/pkg/git"
+	"github.com/kzmshx/tarm/pkg/tarm"
 )
 
 func main() {
+	// The action runs in its own directory, so relative paths are resolved against the workspace.
+	workspace := os.Getenv("GITHUB_WORKSPACE")
+	root := os.Getenv("INPUT_ROOT")
 	cfg := tarm.Config{
-		Root:                  os.Getenv("INPUT_ROOT"),
+		Root:                  workspacePath(workspace, root),
 		RootModulePatterns:    tarm.ParseMultilineInput(os.Getenv("INPUT_ROOT_MODULE_PATTERNS")),
 		ExcludeModulePatterns: tarm.ParseMultilineInput(os.Getenv("INPUT_EXCLUDE_MODULE_PATTERNS")),
 		ChangedFiles:          tarm.ParseMultilineInput(os.Getenv("INPUT_CHANGED_FILES")),
+		IgnorePatterns:        tarm.ParseMultilineInput(os.Getenv("INPUT_IGNORE")),
+		IgnoreFile:            os.Getenv("INPUT_IGNORE_FILE"),
+		TerraformIgnore:       os.Getenv("INPUT_TERRAFORMIGNORE") == "true",
 		DetectChanges:         os.Getenv("INPUT_DETECT_CHANGES") != "false",
 		BaseRef:               os.Getenv("INPUT_BASE_REF"),
 		HeadRef:               os.Getenv("INPUT_HEAD_REF"),
 		OutputFormat:          os.Getenv("INPUT_OUTPUT_FORMAT"),
+		OpenTofu:              os.Getenv("INPUT_OPENTOFU") == "true",
+		InheritedFilePatterns: tarm.ParseMultilineInput(os.Getenv("INPUT_INHERITED_FILE_PATTERNS")),
+		OnParseError:          tarm.ParseErrorPolicy(os.Getenv("INPUT_ON_PARSE_ERROR")),
+		FailOnCycles:          os.Getenv("INPUT_FAIL_ON_CYCLES") == "true",
+		CacheDir:              os.Getenv("INPUT_CACHE_DIR"),
+		FailOnUnmapped:        tarm.ParseMultilineInput(os.Getenv("INPUT_FAIL_ON_UNMAPPED")),
+		Strict:                os.Getenv("INPUT_STRICT") == "true",
+	}
+	cfg.DiagnosticHandler = func(d tarm.Diagnostic) {
+		annotate(root, d)
+	}
+
+	failOn, err := tarm.ParseDiagnosticCodes(tarm.ParseMultilineInput(os.Getenv("INPUT_FAIL_ON")))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	cfg.FailOn = failOn
+	if cfg.CacheDir == "" {
+		cfg.CacheDir = tarm.DefaultCacheDir
+	}
+	cfg.CacheDir = workspacePath(workspace, cfg.CacheDir)
+	if os.Getenv("INPUT_NO_CACHE") == "true" {
+		cfg.CacheDir = ""
+	}
+
+	for _, t := range tarm.ParseMultilineInput(os.Getenv("INPUT_GLOBAL_TRIGGERS")) {
+		trigger, err := tarm.ParseGlobalTrigger(t)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.GlobalTriggers = append(cfg.GlobalTriggers, trigger)
+	}
+
+	for _, r := range tarm.ParseMultilineInput(os.Getenv("INPUT_SOURCE_REWRITES")) {
+		rule, err := tarm.ParseSourceRewrite(r)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.SourceRewrites = append(cfg.SourceRewrites, rule)
 	}
 
+	for _, t := range tarm.ParseMultilineInput(os.Getenv("INPUT_TARGETS")) {
+		rule, err := tarm.ParseTargetRule(t)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.Targets = append(cfg.Targets, rule)
+	}
+
+	if v := os.Getenv("INPUT_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: invalid concurrency %q: %v\n", v, err)
+			os.Exit(1)
+		}
+		cfg.Concurrency = n
+	}
+
+	pathBase, err := tarm.ParsePathBase(os.Getenv("INPUT_PATH_BASE"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	cfg.PathBase = pathBase
+
 	if cfg.BaseRef == "" {
 		cfg.BaseRef = "origin/main"
 	}
@@ -32,20 +109,65 @@ func main() {
 		provider = &git.DiffProvider{
 			BaseRef: cfg.BaseRef,
 			HeadRef: cfg.HeadRef,
+			Dir:     cfg.Root,
 		}
 	}
 
-	result, err := tarm.Run(cfg, provider)
+	result, err := tarm.Run(context.Background(), cfg, provider)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
 		os.Exit(1)
 	}
 
-	writeGitHubOutputs(result)
-	writeStdout(cfg.OutputFormat, result)
+	markdownOpts := []formatter.MarkdownOption{
+		formatter.WithUnmappedFiles(result.UnmappedFiles()),
+		formatter.WithIgnoredFiles(result.IgnoredFiles()),
+	}
+	if os.Getenv("INPUT_MERMAID_DIAGRAM") == "true" {
+		markdownOpts = append(markdownOpts, formatter.WithDiagram(formatter.DefaultMaxFanOut))
+	}
+
+	doc := tarm.NewResultDocument(cfg, result)
+	// Report the root as given rather than resolved against the workspace.
+	doc.Root = cmp.Or(root, ".")
+	writeGitHubOutputs(result, doc, markdownOpts)
+	writeStdout(cfg.OutputFormat, result, doc)
 }
 
-func writeGitHubOutputs(r *tarm.Result) {
+// workspacePath resolves a relative path against the workspace directory, or the current
+// directory if the workspace is unknown.
+func workspacePath(workspace, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	if workspace == "" {
+		if abs, err := filepath.Abs(p); err == nil {
+			return abs
+		}
+		return p
+	}
+	return filepath.Join(workspace, p)
+}
+
+// annotate prints a diagnostic as a workflow command, so that it is shown as an annotation
+// on the file it concerns.
+func annotate(root string, d tarm.Diagnostic) {
+	command := "warning"
+	if d.Severity == tarm.SeverityError {
+		command = "error"
+	}
+	props := []string{"title=tarm " + string(d.Code)}
+	if d.File != "" {
+		props = append(props, "file="+filepath.ToSlash(filepath.Join(root, d.File)))
+		if d.Line > 0 {
+			props = append(props, fmt.Sprintf("line=%d", d.Line))
+		}
+	}
+	message := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(d.Message)
+	fmt.Fprintf(os.Stderr, "::%s %s::%s\n", command, strings.Join(props, ","), message)
+}
+
+func writeGitHubOutputs(r *tarm.Result, doc *tarm.ResultDocument, markdownOpts []formatter.MarkdownOption) {
 	outPath := os.Getenv("GITHUB_OUTPUT")
 	if outPath == "" {
 		return
@@ -68,29 +190,78 @@ func writeGitHubOutputs(r *tarm.Result) {
 	fmt.Fprintf(f, "affected-count=%d\n", len(r.AffectedModules))
 	fmt.Fprintf(f, "has-affected-modules=%t\n", len(r.AffectedModules) > 0)
 
+	cyclesJSON, _ := json.Marshal(doc.Cycles)
+	fmt.Fprintf(f, "cycles-json=%s\n", string(cyclesJSON))
+	fmt.Fprintf(f, "has-cycles=%t\n", len(r.Cycles) > 0)
+
+	diagnosticsJSON, _ := json.Marshal(doc.Diagnostics)
+	fmt.Fprintf(f, "diagnostics-json=%s\n", string(diagnosticsJSON))
+
+	unmappedJSON, _ := json.Marshal(doc.UnmappedFiles)
+	fmt.Fprintf(f, "unmapped-files-json=%s\n", string(unmappedJSON))
+	fmt.Fprintf(f, "has-unmapped-files=%t\n", len(doc.UnmappedFiles) > 0)
+
+	ignoredJSON, _ := json.Marshal(doc.IgnoredFiles)
+	fmt.Fprintf(f, "ignored-files-json=%s\n", string(ignoredJSON))
+	fmt.Fprintf(f, "has-ignored-files=%t\n", len(doc.IgnoredFiles) > 0)
+
+	resultJSON, _ := json.Marshal(doc)
+	fmt.Fprintf(f, "result-json=%s\n", string(resultJSON))
+
 	matrix := make([]map[string]string, 0, len(r.AffectedModules))
 	for _, m := range r.AffectedModules {
-		matrix = append(matrix, map[string]string{"module": m.Path})
+		if len(m.Targets) == 0 {
+			matrix = append(matrix, map[string]string{"module": m.Path})
+		}
+		for _, target := range m.Targets {
+			matrix = append(matrix, map[string]string{
+				"module":    m.Path,
+				"var_file":  target.VarFile,
+				"workspace": target.Workspace,
+			})
+		}
 	}
 	matrixJSON, _ := json.Marshal(map[string]any{"include": matrix})
 	fmt.Fprintf(f, "matrix=%s\n", string(matrixJSON))
 
-	markdown := formatter.Markdown(r.AffectedModules)
+	markdown := formatter.Markdown(r.AffectedModules, markdownOpts...)
 	fmt.Fprintf(f, "markdown-summary=%s\n", strings.ReplaceAll(markdown, "\n", "%0A"))
 }
 
-func writeStdout(format string, r *tarm.Result) {
+func writeStdout(format string, r *tarm.Result, doc *tarm.ResultDocument) {
 	if format == "json" {
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
-		enc.Encode(map[string]any{
-			"affected_modules": r.AffectedModules,
-		})
+		enc.Encode(doc)
 	} else {
 		for _, m := range r.AffectedModules {
-			fmt.Printf("## %s\n", m.Path)
+			if m.Deleted {
+				fmt.Printf("## %s (deleted)\n", m.Path)
+			} else {
+				fmt.Printf("## %s\n", m.Path)
+			}
 			for _, cause := range m.AffectedBy {
-				fmt.Printf("- %s\n", cause)
+				fmt.Printf("- %s\n", formatter.Cause(m, cause))
+				for _, chain := range m.ChainsFor(cause) {
+					fmt.Printf("    via %s\n", chain)
+				}
+			}
+			for _, target := range m.Targets {
+				fmt.Printf("- target %s\n", target)
+			}
+			fmt.Println()
+		}
+		if unmapped := r.UnmappedFiles(); len(unmapped) > 0 {
+			fmt.Println("## Unmapped files")
+			for _, f := range unmapped {
+				fmt.Printf("- %s\n", formatter.ChangedFile(f))
+			}
+			fmt.Println()
+		}
+		if ignored := r.IgnoredFiles(); len(ignored) > 0 {
+			fmt.Println("## Ignored files")
+			for _, f := range ignored {
+				fmt.Printf("- %s\n", formatter.ChangedFile(f))
 			}
 			fmt.Println()
 		}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspacePath(t *testing.T) {
+	workspace := filepath.FromSlash("/home/runner/work/repo/repo")
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		workspace string
+		path      string
+		want      string
+	}{
+		{name: "relative path", workspace: workspace, path: ".tarm-cache", want: filepath.Join(workspace, ".tarm-cache")},
+		{name: "root", workspace: workspace, path: ".", want: workspace},
+		{name: "empty root", workspace: workspace, path: "", want: workspace},
+		{name: "nested relative path", workspace: workspace, path: "infra/../.cache/tarm", want: filepath.Join(workspace, ".cache", "tarm")},
+		{name: "absolute path", workspace: workspace, path: filepath.FromSlash("/tmp/tarm-cache"), want: filepath.FromSlash("/tmp/tarm-cache")},
+		{name: "no workspace", path: ".tarm-cache", want: filepath.Join(cwd, ".tarm-cache")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := workspacePath(tt.workspace, tt.path); got != tt.want {
+				t.Errorf("workspacePath(%q, %q) = %q, want %q", tt.workspace, tt.path, got, tt.want)
+			}
+		})
+	}
+}
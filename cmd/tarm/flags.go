@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kzmshx/tarm/pkg/git"
+	"github.com/kzmshx/tarm/pkg/tarm"
+)
+
+// analysisFlags are the flags shared by the commands that analyse changes: tarm, why and graph.
+type analysisFlags struct {
+	root                  string
+	rootModulePatterns    stringSlice
+	excludeModulePatterns stringSlice
+	changedFiles          stringSlice
+	ignorePatterns        stringSlice
+	ignoreFile            string
+	terraformIgnore       bool
+	detectChanges         bool
+	baseRef               string
+	headRef               string
+	openTofu              bool
+	concurrency           int
+	cacheDir              string
+	noCache               bool
+	pathBase              string
+	targets               stringSlice
+	inheritedFiles        stringSlice
+	globalTriggers        stringSlice
+	sourceRewrites        stringSlice
+	onParseError          string
+}
+
+// registerAnalysisFlags defines the analysis flags on fs. changedFilesUsage describes --changed-files.
+func registerAnalysisFlags(fs *flag.FlagSet, changedFilesUsage string) *analysisFlags {
+	f := &analysisFlags{}
+	fs.StringVar(&f.root, "root", ".", "Root directory to search for Terraform files")
+	fs.Var(&f.rootModulePatterns, "root-module-patterns", "Glob pattern for root modules (repeatable)")
+	fs.Var(&f.excludeModulePatterns, "exclude-module-patterns", "Glob pattern for modules to exclude (repeatable)")
+	fs.Var(&f.changedFiles, "changed-files", changedFilesUsage)
+	fs.Var(&f.ignorePatterns, "ignore", "Pattern in gitignore syntax for changed files to ignore (repeatable)")
+	fs.StringVar(&f.ignoreFile, "ignore-file", tarm.DefaultIgnoreFile, "File of patterns in gitignore syntax for changed files to ignore, if it exists")
+	fs.BoolVar(&f.terraformIgnore, "terraformignore", false, "Ignore changed files matched by the .terraformignore file of the module they belong to")
+	fs.BoolVar(&f.detectChanges, "detect-changes", false, "Auto-detect changed files via git diff")
+	fs.StringVar(&f.baseRef, "base-ref", "origin/main", "Base ref for change detection")
+	fs.StringVar(&f.headRef, "head-ref", "HEAD", "Head ref for change detection")
+	fs.BoolVar(&f.openTofu, "opentofu", false, "Load configuration as OpenTofu (.tofu files shadow .tf files)")
+	fs.IntVar(&f.concurrency, "concurrency", 0, "Number of modules parsed concurrently (default GOMAXPROCS)")
+	fs.StringVar(&f.cacheDir, "cache-dir", tarm.DefaultCacheDir, "Directory to cache parsed modules in")
+	fs.BoolVar(&f.noCache, "no-cache", false, "Parse every module without reading or writing the cache")
+	fs.StringVar(&f.pathBase, "path-base", "root", "Directory paths and patterns are relative to: root or repository")
+	fs.Var(&f.inheritedFiles, "inherited-file-patterns", "Glob pattern for files affecting every root module beneath their directory (repeatable)")
+	fs.Var(&f.globalTriggers, "global-trigger", "Glob pattern for files affecting every root module <pattern>[=<root-module-pattern>;...] (repeatable)")
+	fs.Var(&f.sourceRewrites, "source-rewrite", "Rewrite remote module sources to a local directory <pattern>=<path>[;floating-only] (repeatable)")
+	fs.StringVar(&f.onParseError, "on-parse-error", "skip", "Handling of modules that fail to parse: skip, treat-as-affected or fail")
+	fs.Var(&f.targets, "target", "Deployment target rule <root-module-pattern>:var-file=<glob>;workspace=<name> (repeatable)")
+	return f
+}
+
+// config builds the analysis configuration from the parsed flags, exiting on invalid values.
+func (f *analysisFlags) config() tarm.Config {
+	var targetRules []tarm.TargetRule
+	for _, t := range f.targets {
+		rule, err := tarm.ParseTargetRule(t)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		targetRules = append(targetRules, rule)
+	}
+
+	parseErrorPolicy, err := tarm.ParseParseErrorPolicy(f.onParseError)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The root is made absolute so that git, the analysis and the rebasing of the paths git
+	// reports agree on the directory.
+	root, err := filepath.Abs(f.root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg := tarm.Config{
+		Root:                  root,
+		RootModulePatterns:    f.rootModulePatterns,
+		ExcludeModulePatterns: f.excludeModulePatterns,
+		ChangedFiles:          f.changedFiles,
+		IgnorePatterns:        f.ignorePatterns,
+		IgnoreFile:            f.ignoreFile,
+		TerraformIgnore:       f.terraformIgnore,
+		DetectChanges:         f.detectChanges,
+		BaseRef:               f.baseRef,
+		HeadRef:               f.headRef,
+		OpenTofu:              f.openTofu,
+		Concurrency:           f.concurrency,
+		CacheDir:              f.cacheDir,
+		DiagnosticHandler:     printDiagnostic,
+		InheritedFilePatterns: f.inheritedFiles,
+		GlobalTriggers:        parseGlobalTriggers(f.globalTriggers),
+		SourceRewrites:        parseSourceRewrites(f.sourceRewrites),
+		OnParseError:          parseErrorPolicy,
+		Targets:               targetRules,
+	}
+	if f.noCache {
+		cfg.CacheDir = ""
+	}
+	cfg.PathBase = parsePathBase(f.pathBase)
+	return cfg
+}
+
+// changedFilesProvider returns the provider detecting the changed files of cfg, or nil if
+// change detection is disabled.
+func changedFilesProvider(cfg tarm.Config) git.ChangedFilesProvider {
+	if !cfg.DetectChanges {
+		return nil
+	}
+	return &git.DiffProvider{
+		BaseRef: cfg.BaseRef,
+		HeadRef: cfg.HeadRef,
+		Dir:     cfg.Root,
+	}
+}
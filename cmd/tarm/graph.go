@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/kzmshx/tarm/internal/formatter"
+	"github.com/kzmshx/tarm/pkg/tarm"
+)
+
+// runGraph exports the dependency graph as DOT, Mermaid or JSON.
+func runGraph(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+
+	analysis := registerAnalysisFlags(fs, "Path to treat as changed; changed and affected nodes are highlighted (repeatable)")
+
+	var (
+		format string
+		focus  stringSlice
+	)
+
+	fs.StringVar(&format, "format", "dot", "Output format: dot, mermaid or json")
+	fs.Var(&focus, "focus", "Only export this module and the nodes connected to it (repeatable)")
+	fs.Parse(args)
+
+	if !slices.Contains([]string{"dot", "mermaid", "json"}, format) {
+		fmt.Fprintf(os.Stderr, "error: unknown format %q: must be one of dot, mermaid, json\n", format)
+		os.Exit(1)
+	}
+
+	cfg := analysis.config()
+	provider := changedFilesProvider(cfg)
+
+	doc, err := tarm.ExportGraph(ctx, cfg, provider, focus)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch format {
+	case "dot":
+		fmt.Print(formatter.DOT(doc))
+	case "mermaid":
+		fmt.Print(formatter.Mermaid(doc))
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(doc)
+	}
+}
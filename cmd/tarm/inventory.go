@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/kzmshx/tarm/pkg/tarm"
+)
+
+// runInventory prints every module call in the repository with its parsed source as JSON.
+func runInventory(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("inventory", flag.ExitOnError)
+
+	var (
+		root           string
+		openTofu       bool
+		concurrency    int
+		cacheDir       string
+		noCache        bool
+		pathBase       string
+		sourceRewrites stringSlice
+		kinds          stringSlice
+	)
+
+	fs.StringVar(&root, "root", ".", "Root directory to search for Terraform files")
+	fs.BoolVar(&openTofu, "opentofu", false, "Load configuration as OpenTofu (.tofu files shadow .tf files)")
+	fs.IntVar(&concurrency, "concurrency", 0, "Number of modules parsed concurrently (default GOMAXPROCS)")
+	fs.StringVar(&cacheDir, "cache-dir", tarm.DefaultCacheDir, "Directory to cache parsed modules in")
+	fs.BoolVar(&noCache, "no-cache", false, "Parse every module without reading or writing the cache")
+	fs.StringVar(&pathBase, "path-base", "root", "Directory paths and patterns are relative to: root or repository")
+	fs.Var(&sourceRewrites, "source-rewrite", "Rewrite remote module sources to a local directory <pattern>=<path>[;floating-only] (repeatable)")
+	fs.Var(&kinds, "kind", "Only list sources of this kind, e.g. git or registry (repeatable)")
+	fs.Parse(args)
+
+	cfg := tarm.Config{
+		Root:              root,
+		OpenTofu:          openTofu,
+		Concurrency:       concurrency,
+		CacheDir:          cacheDir,
+		DiagnosticHandler: printDiagnostic,
+		SourceRewrites:    parseSourceRewrites(sourceRewrites),
+	}
+	if noCache {
+		cfg.CacheDir = ""
+	}
+	cfg.PathBase = parsePathBase(pathBase)
+
+	calls, err := tarm.Inventory(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	filtered := make([]tarm.ModuleCall, 0, len(calls))
+	for _, call := range calls {
+		if len(kinds) == 0 || slices.Contains(kinds, string(call.Source.Kind)) {
+			filtered = append(filtered, call)
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(filtered)
+}
@@ -1,14 +1,16 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
 
-	"github.com/kzmshx/tarm/internal/git"
-	"github.com/kzmshx/tarm/internal/tarm"
+	"github.com/kzmshx/tarm/internal/formatter"
+	"github.com/kzmshx/tarm/pkg/tarm"
 )
 
 type stringSlice []string
@@ -20,53 +22,63 @@ func (s *stringSlice) Set(v string) error {
 }
 
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "inventory":
+			runInventory(ctx, os.Args[2:])
+			return
+		case "why":
+			runWhy(ctx, os.Args[2:])
+			return
+		case "graph":
+			runGraph(ctx, os.Args[2:])
+			return
+		}
+	}
+
+	analysis := registerAnalysisFlags(flag.CommandLine, "Path to treat as changed (repeatable)")
+
 	var (
-		root                  string
-		rootModulePatterns    stringSlice
-		excludeModulePatterns stringSlice
-		changedFiles          stringSlice
-		detectChanges         bool
-		baseRef               string
-		headRef               string
-		outputFormat          string
+		outputFormat   string
+		verbose        bool
+		failOnCycles   bool
+		failOnUnmapped stringSlice
+		strict         bool
+		failOn         stringSlice
 	)
 
-	flag.StringVar(&root, "root", ".", "Root directory to search for Terraform files")
-	flag.Var(&rootModulePatterns, "root-module-patterns", "Glob pattern for root modules (repeatable)")
-	flag.Var(&excludeModulePatterns, "exclude-module-patterns", "Glob pattern for modules to exclude (repeatable)")
-	flag.Var(&changedFiles, "changed-files", "Path to treat as changed (repeatable)")
-	flag.BoolVar(&detectChanges, "detect-changes", false, "Auto-detect changed files via git diff")
-	flag.StringVar(&baseRef, "base-ref", "origin/main", "Base ref for change detection")
-	flag.StringVar(&headRef, "head-ref", "HEAD", "Head ref for change detection")
 	flag.StringVar(&outputFormat, "output-format", "text", "Output format: text or json")
+	flag.BoolVar(&verbose, "verbose", false, "In text output, list the causes and dependency chains of every affected root module")
+	flag.BoolVar(&failOnCycles, "fail-on-cycles", false, "Exit with an error listing the module calls of any dependency cycle")
+	flag.Var(&failOnUnmapped, "fail-on-unmapped", "Exit with an error if changed files within this directory map to no module (repeatable)")
+	flag.BoolVar(&strict, "strict", false, "Exit with an error if any diagnostic is reported")
+	flag.Var(&failOn, "fail-on", "Exit with an error if a diagnostic with one of these comma separated codes is reported (repeatable)")
 	flag.Parse()
 
-	if len(rootModulePatterns) == 0 {
+	if len(analysis.rootModulePatterns) == 0 {
 		fmt.Fprintln(os.Stderr, "error: at least one --root-module-patterns is required")
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	cfg := tarm.Config{
-		Root:                  root,
-		RootModulePatterns:    rootModulePatterns,
-		ExcludeModulePatterns: excludeModulePatterns,
-		ChangedFiles:          changedFiles,
-		DetectChanges:         detectChanges,
-		BaseRef:               baseRef,
-		HeadRef:               headRef,
-		OutputFormat:          outputFormat,
+	failOnCodes, err := tarm.ParseDiagnosticCodes(failOn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
 	}
 
-	var provider git.ChangedFilesProvider
-	if cfg.DetectChanges {
-		provider = &git.DiffProvider{
-			BaseRef: cfg.BaseRef,
-			HeadRef: cfg.HeadRef,
-		}
-	}
+	cfg := analysis.config()
+	cfg.OutputFormat = outputFormat
+	cfg.FailOnCycles = failOnCycles
+	cfg.FailOnUnmapped = failOnUnmapped
+	cfg.Strict = strict
+	cfg.FailOn = failOnCodes
+	provider := changedFilesProvider(cfg)
 
-	result, err := tarm.Run(cfg, provider)
+	result, err := tarm.Run(ctx, cfg, provider)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
@@ -76,10 +88,77 @@ func main() {
 	case "json":
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
-		enc.Encode(result.AffectedModules)
+		doc := tarm.NewResultDocument(cfg, result)
+		// Report the root as given rather than made absolute.
+		doc.Root = analysis.root
+		enc.Encode(doc)
 	default:
 		for _, m := range result.AffectedModules {
-			fmt.Println(m.Path)
+			if len(m.Targets) == 0 {
+				fmt.Println(m.Path)
+			}
+			for _, target := range m.Targets {
+				fmt.Printf("%s %s\n", m.Path, target)
+			}
+			if verbose {
+				for _, cause := range m.AffectedBy {
+					fmt.Printf("  - %s\n", formatter.Cause(m, cause))
+					for _, chain := range m.ChainsFor(cause) {
+						fmt.Printf("      via %s\n", chain)
+					}
+				}
+			}
+		}
+		// Unmapped and ignored files go to stderr so that stdout remains a list of root modules.
+		for _, f := range result.UnmappedFiles() {
+			fmt.Fprintf(os.Stderr, "unmapped: %s\n", formatter.ChangedFile(f))
+		}
+		for _, f := range result.IgnoredFiles() {
+			fmt.Fprintf(os.Stderr, "ignored: %s\n", formatter.ChangedFile(f))
+		}
+	}
+}
+
+// printDiagnostic prints an analysis diagnostic to stderr.
+func printDiagnostic(d tarm.Diagnostic) {
+	prefix := "WARN"
+	if d.Severity == tarm.SeverityError {
+		prefix = "ERROR"
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s\n", prefix, d)
+}
+
+func parsePathBase(value string) tarm.PathBase {
+	pathBase, err := tarm.ParsePathBase(value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	return pathBase
+}
+
+func parseSourceRewrites(values []string) []tarm.SourceRewrite {
+	var rules []tarm.SourceRewrite
+	for _, v := range values {
+		rule, err := tarm.ParseSourceRewrite(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func parseGlobalTriggers(values []string) []tarm.GlobalTrigger {
+	var triggers []tarm.GlobalTrigger
+	for _, v := range values {
+		trigger, err := tarm.ParseGlobalTrigger(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
 		}
+		triggers = append(triggers, trigger)
 	}
+	return triggers
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kzmshx/tarm/pkg/tarm"
+)
+
+// runWhy explains why a root module is or is not affected by the changed paths.
+func runWhy(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("why", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: tarm why [flags] <root-module> [changed-path...]")
+		fs.PrintDefaults()
+	}
+
+	analysis := registerAnalysisFlags(fs, "Path to treat as changed (repeatable)")
+	var outputFormat string
+	fs.StringVar(&outputFormat, "output-format", "text", "Output format: text or json")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "error: a root module is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	cfg := analysis.config()
+	cfg.ChangedFiles = append(cfg.ChangedFiles, fs.Args()[1:]...)
+	provider := changedFilesProvider(cfg)
+
+	e, err := tarm.Why(ctx, cfg, provider, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputFormat == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(e)
+		return
+	}
+
+	switch e.Status {
+	case tarm.WhyAffected:
+		fmt.Printf("%s is affected\n", e.Root)
+	case tarm.WhyExcluded:
+		fmt.Printf("%s is not affected: it is excluded by --exclude-module-patterns\n", e.Root)
+	case tarm.WhyPatternMismatch:
+		fmt.Printf("%s is not affected: it matches no --root-module-patterns and is not a Terragrunt unit\n", e.Root)
+	default:
+		if len(e.Paths) == 0 {
+			fmt.Printf("%s is not affected: no changed paths\n", e.Root)
+		} else {
+			fmt.Printf("%s is not affected: no changed path reaches it\n", e.Root)
+		}
+	}
+
+	for _, p := range e.Paths {
+		fmt.Printf("  %s: %s\n", p.Path, describePath(e.Root, p))
+		for _, chain := range p.Chains {
+			fmt.Printf("      via %s\n", chain)
+		}
+	}
+}
+
+// describePath describes the outcome of a changed path for the root module.
+func describePath(rootModule string, p tarm.PathExplanation) string {
+	switch p.Status {
+	case tarm.WhyAffected:
+		return "affects " + rootModule
+	case tarm.WhyOutsideRoot:
+		return "outside the root directory"
+	case tarm.WhyNoModule:
+		return "no parent directory contains .tf files"
+	case tarm.WhyShadowed:
+		return "not loaded in the current mode (shadowed by an OpenTofu file, or an OpenTofu file without --opentofu)"
+	case tarm.WhyIgnored:
+		return "ignored by " + p.IgnoredBy
+	default:
+		sources := make([]string, 0, len(p.Sources))
+		for _, s := range p.Sources {
+			sources = append(sources, s.Path)
+		}
+		return fmt.Sprintf("maps to %s, but no dependency path leads there from %s", strings.Join(sources, ", "), rootModule)
+	}
+}
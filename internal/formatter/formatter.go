@@ -6,7 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/kzmshx/tarm/internal/tarm"
+	"github.com/kzmshx/tarm/pkg/tarm"
 )
 
 // JSON marshals the affected root modules to a JSON string.
@@ -21,31 +21,155 @@ func JSON(modules []tarm.AffectedRootModule) string {
 	return string(b)
 }
 
+// MarkdownOption configures Markdown.
+type MarkdownOption func(*markdownOptions)
+
+type markdownOptions struct {
+	diagram   bool
+	maxFanOut int
+	unmapped  []tarm.ChangedFile
+	ignored   []tarm.ChangedFile
+}
+
+// WithDiagram adds a Mermaid flowchart of the changed modules, the intermediate modules and
+// the affected root modules. More than maxFanOut root modules with the same dependencies are
+// collapsed into a single node; zero selects DefaultMaxFanOut.
+func WithDiagram(maxFanOut int) MarkdownOption {
+	return func(o *markdownOptions) {
+		o.diagram = true
+		o.maxFanOut = maxFanOut
+	}
+}
+
+// WithUnmappedFiles lists the changed files that map to no module, so that it is visible
+// which changes were not considered.
+func WithUnmappedFiles(files []tarm.ChangedFile) MarkdownOption {
+	return func(o *markdownOptions) {
+		o.unmapped = files
+	}
+}
+
+// WithIgnoredFiles lists the changed files matched by an ignore rule, along with the rule.
+func WithIgnoredFiles(files []tarm.ChangedFile) MarkdownOption {
+	return func(o *markdownOptions) {
+		o.ignored = files
+	}
+}
+
 // Markdown generates a GitHub-flavored markdown summary of the affected root modules.
-func Markdown(modules []tarm.AffectedRootModule) string {
+func Markdown(modules []tarm.AffectedRootModule, opts ...MarkdownOption) string {
+	var o markdownOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	var sb strings.Builder
 
 	sb.WriteString("## Terraform Affected Root Modules\n\n")
 
 	if len(modules) == 0 {
 		sb.WriteString("No affected root modules found.\n")
+		if len(o.unmapped) > 0 || len(o.ignored) > 0 {
+			sb.WriteString("\n")
+		}
+		writeChangedFiles(&sb, o.unmapped, "changed file(s) not mapped to any module")
+		if len(o.unmapped) > 0 && len(o.ignored) > 0 {
+			sb.WriteString("\n")
+		}
+		writeChangedFiles(&sb, o.ignored, "changed file(s) ignored")
 		return sb.String()
 	}
 
 	sb.WriteString(fmt.Sprintf("**%d** root module(s) affected:\n\n", len(modules)))
 
+	if o.diagram {
+		sb.WriteString("```mermaid\n")
+		sb.WriteString(mermaid(AffectedGraph(modules, o.maxFanOut)))
+		sb.WriteString("```\n\n")
+	}
+
 	for _, module := range modules {
-		sb.WriteString(fmt.Sprintf("<details><summary>%s</summary>\n\n", module.Path))
+		summary := module.Path
+		if module.Deleted {
+			summary += " (deleted)"
+		}
+		sb.WriteString(fmt.Sprintf("<details><summary>%s</summary>\n\n", summary))
 		sb.WriteString("```\nBecause of:\n")
 		for _, cause := range tarm.Unique(module.AffectedBy) {
-			sb.WriteString(fmt.Sprintf("- %s\n", cause))
+			sb.WriteString(fmt.Sprintf("- %s\n", Cause(module, cause)))
+			for _, chain := range module.ChainsFor(cause) {
+				sb.WriteString(fmt.Sprintf("    via %s\n", chain))
+			}
+		}
+		if len(module.Targets) > 0 {
+			sb.WriteString("Targets:\n")
+			for _, target := range module.Targets {
+				sb.WriteString(fmt.Sprintf("- %s\n", target))
+			}
 		}
 		sb.WriteString("```\n\n</details>\n\n")
 	}
+	writeChangedFiles(&sb, o.unmapped, "changed file(s) not mapped to any module")
+	if len(o.unmapped) > 0 && len(o.ignored) > 0 {
+		sb.WriteString("\n")
+	}
+	writeChangedFiles(&sb, o.ignored, "changed file(s) ignored")
 
 	return sb.String()
 }
 
+func writeChangedFiles(sb *strings.Builder, files []tarm.ChangedFile, summary string) {
+	if len(files) == 0 {
+		return
+	}
+	sb.WriteString(fmt.Sprintf("<details><summary>%d %s</summary>\n\n", len(files), summary))
+	sb.WriteString("```\n")
+	for _, f := range files {
+		sb.WriteString(fmt.Sprintf("- %s\n", ChangedFile(f)))
+	}
+	sb.WriteString("```\n\n</details>\n")
+}
+
+// ChangedFile returns a human-readable description of a changed file that maps to no module or
+// is ignored, annotated with the reason.
+func ChangedFile(f tarm.ChangedFile) string {
+	switch f.Status {
+	case tarm.ChangeOutsideRoot:
+		return fmt.Sprintf("%s (outside the root directory)", f.Path)
+	case tarm.ChangeNoModule:
+		return fmt.Sprintf("%s (no parent directory contains .tf files)", f.Path)
+	case tarm.ChangeShadowed:
+		return fmt.Sprintf("%s (not loaded in the current mode)", f.Path)
+	case tarm.ChangeIgnored:
+		return fmt.Sprintf("%s (ignored by %s)", f.Path, f.IgnoredBy)
+	default:
+		return f.Path
+	}
+}
+
+// Cause returns a human-readable description of a path affecting the module,
+// annotated with its reason unless the path is a plain module change.
+func Cause(module tarm.AffectedRootModule, path string) string {
+	switch module.ReasonFor(path) {
+	case tarm.ReasonFileReference:
+		return fmt.Sprintf("%s (referenced file)", path)
+	case tarm.ReasonRemoteState:
+		return fmt.Sprintf("%s (via terraform_remote_state)", path)
+	case tarm.ReasonDependency:
+		return fmt.Sprintf("%s (via Terragrunt dependency)", path)
+	case tarm.ReasonVarFile:
+		return fmt.Sprintf("%s (var file)", path)
+	case tarm.ReasonInherited:
+		return fmt.Sprintf("%s (inherited file)", path)
+	case tarm.ReasonParseError:
+		return fmt.Sprintf("%s (failed to parse)", path)
+	case tarm.ReasonGlobalTrigger:
+		return fmt.Sprintf("%s (global trigger)", path)
+	default:
+		return path
+	}
+}
+
 // FindParentModule extracts the module path from a file path based on known directory conventions.
 func FindParentModule(file string) string {
 	dir := filepath.Dir(file)
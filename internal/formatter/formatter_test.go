@@ -5,7 +5,7 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/kzmshx/tarm/internal/tarm"
+	"github.com/kzmshx/tarm/pkg/tarm"
 )
 
 func TestJSON(t *testing.T) {
@@ -47,6 +47,7 @@ func TestMarkdown(t *testing.T) {
 	tests := []struct {
 		name         string
 		modules      []tarm.AffectedRootModule
+		opts         []MarkdownOption
 		wantContains []string
 		wantAbsent   []string
 	}{
@@ -69,6 +70,86 @@ func TestMarkdown(t *testing.T) {
 			},
 			wantContains: []string{"**2** root module(s) affected:", "environments/dev/api", "environments/prod/api"},
 		},
+		{
+			name:         "deleted module",
+			modules:      []tarm.AffectedRootModule{{Path: "environments/dev/legacy", AffectedBy: []string{"environments/dev/legacy"}, Deleted: true}},
+			wantContains: []string{"<details><summary>environments/dev/legacy (deleted)</summary>"},
+		},
+		{
+			name: "module with targets",
+			modules: []tarm.AffectedRootModule{{
+				Path:       "services/web",
+				AffectedBy: []string{"services/web/envs/dev.tfvars"},
+				Causes:     []tarm.Cause{{Path: "services/web/envs/dev.tfvars", Reason: tarm.ReasonVarFile}},
+				Targets:    []tarm.Target{{VarFile: "envs/dev.tfvars", Workspace: "dev"}},
+			}},
+			wantContains: []string{"- services/web/envs/dev.tfvars (var file)", "Targets:\n- var-file=envs/dev.tfvars workspace=dev"},
+		},
+		{
+			name: "dependency chains",
+			modules: []tarm.AffectedRootModule{{
+				Path:       "environments/dev/api",
+				AffectedBy: []string{"modules/common"},
+				Chains: []tarm.Chain{{Cause: "modules/common", Steps: []tarm.ChainStep{
+					{From: "environments/dev/api", To: "modules/database", Kind: tarm.EdgeModuleCall, Block: "module.database", File: "environments/dev/api/main.tf", Line: 6},
+					{From: "modules/database", To: "modules/common", Kind: tarm.EdgeModuleCall, Block: "module.common", File: "modules/database/main.tf", Line: 5},
+				}}},
+			}},
+			wantContains: []string{"- modules/common\n    via environments/dev/api -> module.database (environments/dev/api/main.tf:6) -> modules/database -> module.common (modules/database/main.tf:5) -> modules/common\n"},
+		},
+		{
+			name: "diagram",
+			modules: []tarm.AffectedRootModule{{
+				Path:       "environments/dev/api",
+				AffectedBy: []string{"modules/database"},
+				Chains: []tarm.Chain{{Cause: "modules/database", Steps: []tarm.ChainStep{
+					{From: "environments/dev/api", To: "modules/database", Kind: tarm.EdgeModuleCall},
+				}}},
+			}},
+			opts:         []MarkdownOption{WithDiagram(0)},
+			wantContains: []string{"affected:\n\n```mermaid\nflowchart LR\n", "  n0 --> n1\n", "```\n\n<details>"},
+		},
+		{
+			name:       "no diagram by default",
+			modules:    []tarm.AffectedRootModule{{Path: "environments/dev/api", AffectedBy: []string{"modules/database"}}},
+			wantAbsent: []string{"```mermaid"},
+		},
+		{
+			name:    "unmapped files without affected modules",
+			modules: []tarm.AffectedRootModule{},
+			opts: []MarkdownOption{WithUnmappedFiles([]tarm.ChangedFile{
+				{Path: "scripts/deploy.sh", Status: tarm.ChangeNoModule},
+				{Path: "../README.md", Status: tarm.ChangeOutsideRoot},
+			})},
+			wantContains: []string{
+				"No affected root modules found.\n",
+				"<details><summary>2 changed file(s) not mapped to any module</summary>",
+				"- scripts/deploy.sh (no parent directory contains .tf files)\n- ../README.md (outside the root directory)\n",
+			},
+		},
+		{
+			name:         "unmapped files after affected modules",
+			modules:      []tarm.AffectedRootModule{{Path: "environments/dev/api", AffectedBy: []string{"modules/database"}}},
+			opts:         []MarkdownOption{WithUnmappedFiles([]tarm.ChangedFile{{Path: "main.tofu", Status: tarm.ChangeShadowed}})},
+			wantContains: []string{"</details>\n\n<details><summary>1 changed file(s) not mapped to any module</summary>", "- main.tofu (not loaded in the current mode)"},
+		},
+		{
+			name:    "ignored files after unmapped files",
+			modules: []tarm.AffectedRootModule{},
+			opts: []MarkdownOption{
+				WithUnmappedFiles([]tarm.ChangedFile{{Path: "scripts/deploy.sh", Status: tarm.ChangeNoModule}}),
+				WithIgnoredFiles([]tarm.ChangedFile{{Path: "modules/network/README.md", Status: tarm.ChangeIgnored, IgnoredBy: ".tarmignore:1: *.md"}}),
+			},
+			wantContains: []string{
+				"```\n\n</details>\n\n<details><summary>1 changed file(s) ignored</summary>",
+				"- modules/network/README.md (ignored by .tarmignore:1: *.md)\n",
+			},
+		},
+		{
+			name:       "no unmapped files section by default",
+			modules:    []tarm.AffectedRootModule{},
+			wantAbsent: []string{"not mapped"},
+		},
 		{
 			name:         "deduplicates causes",
 			modules:      []tarm.AffectedRootModule{{Path: "environments/dev/api", AffectedBy: []string{"modules/database", "modules/database", "modules/common"}}},
@@ -78,7 +159,7 @@ func TestMarkdown(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := Markdown(tt.modules)
+			got := Markdown(tt.modules, tt.opts...)
 			for _, want := range tt.wantContains {
 				if !strings.Contains(got, want) {
 					t.Errorf("missing %q in:\n%s", want, got)
@@ -109,3 +190,37 @@ func TestFindParentModule(t *testing.T) {
 		}
 	}
 }
+
+func TestCause(t *testing.T) {
+	module := tarm.AffectedRootModule{
+		Path:       "apps/web",
+		AffectedBy: []string{"apps/web", "policies/web.json", "stacks/shared/vpc", "live/vpc", "backend.hcl", "../.github/workflows/terraform.yml"},
+		Causes: []tarm.Cause{
+			{Path: "apps/web", Reason: tarm.ReasonModule},
+			{Path: "policies/web.json", Reason: tarm.ReasonFileReference},
+			{Path: "stacks/shared/vpc", Reason: tarm.ReasonRemoteState},
+			{Path: "live/vpc", Reason: tarm.ReasonDependency},
+			{Path: "backend.hcl", Reason: tarm.ReasonInherited},
+			{Path: "../.github/workflows/terraform.yml", Reason: tarm.ReasonGlobalTrigger},
+		},
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"apps/web", "apps/web"},
+		{"policies/web.json", "policies/web.json (referenced file)"},
+		{"stacks/shared/vpc", "stacks/shared/vpc (via terraform_remote_state)"},
+		{"live/vpc", "live/vpc (via Terragrunt dependency)"},
+		{"backend.hcl", "backend.hcl (inherited file)"},
+		{"../.github/workflows/terraform.yml", "../.github/workflows/terraform.yml (global trigger)"},
+		{"unknown", "unknown"},
+	}
+	for _, tt := range tests {
+		got := Cause(module, tt.path)
+		if got != tt.want {
+			t.Errorf("Cause(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package formatter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kzmshx/tarm/pkg/tarm"
+)
+
+// DOT renders the graph in the Graphviz DOT language. Root modules are filled boxes, other
+// modules rounded boxes, files notes and external sources dashed ellipses. Changed nodes
+// have a red border and affected nodes are highlighted.
+func DOT(g *tarm.GraphDocument) string {
+	var sb strings.Builder
+
+	sb.WriteString("digraph tarm {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [fontname=\"Helvetica\", fontsize=10];\n")
+	for _, n := range g.Nodes {
+		var shape, style, fill string
+		switch n.Kind {
+		case tarm.NodeRoot:
+			shape, style, fill = "box", "filled,bold", "#dbeafe"
+		case tarm.NodeModule:
+			shape, style = "box", "rounded"
+		case tarm.NodeFile:
+			shape = "note"
+		case tarm.NodeExternal:
+			shape, style = "ellipse", "dashed"
+		}
+		if n.Affected {
+			switch n.Kind {
+			case tarm.NodeRoot:
+				fill = "#fde68a"
+			case tarm.NodeModule:
+				style, fill = "filled,rounded", "#fef3c7"
+			}
+		}
+
+		attrs := []string{"shape=" + shape}
+		if style != "" {
+			attrs = append(attrs, "style="+dotQuote(style))
+		}
+		if fill != "" {
+			attrs = append(attrs, "fillcolor="+dotQuote(fill))
+		}
+		if n.Changed {
+			attrs = append(attrs, `color="#dc2626"`, "penwidth=2")
+		}
+		fmt.Fprintf(&sb, "  %s [%s];\n", dotQuote(n.ID), strings.Join(attrs, ", "))
+	}
+	for _, e := range g.Edges {
+		switch e.Kind {
+		case tarm.EdgeModuleCall:
+			fmt.Fprintf(&sb, "  %s -> %s;\n", dotQuote(e.From), dotQuote(e.To))
+		case tarm.EdgeFileReference:
+			fmt.Fprintf(&sb, "  %s -> %s [style=dotted];\n", dotQuote(e.From), dotQuote(e.To))
+		default:
+			fmt.Fprintf(&sb, "  %s -> %s [style=dashed, label=%s];\n", dotQuote(e.From), dotQuote(e.To), dotQuote(string(e.Kind)))
+		}
+	}
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart, with the same distinctions as DOT
+// expressed through node shapes and classes.
+func Mermaid(g *tarm.GraphDocument) string {
+	return mermaid(g, nil)
+}
+
+// mermaid renders the graph as Mermaid does, labelling the nodes in labels with their label
+// rather than their ID.
+func mermaid(g *tarm.GraphDocument, labels map[string]string) string {
+	var sb strings.Builder
+
+	ids := make(map[string]string, len(g.Nodes))
+	var changed, affected []string
+	sb.WriteString("flowchart LR\n")
+	for i, n := range g.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.ID] = id
+		label := mermaidQuote(n.ID)
+		if l, ok := labels[n.ID]; ok {
+			label = mermaidQuote(l)
+		}
+		switch n.Kind {
+		case tarm.NodeRoot:
+			fmt.Fprintf(&sb, "  %s[%s]:::root\n", id, label)
+		case tarm.NodeModule:
+			fmt.Fprintf(&sb, "  %s(%s):::module\n", id, label)
+		case tarm.NodeFile:
+			fmt.Fprintf(&sb, "  %s>%s]:::file\n", id, label)
+		case tarm.NodeExternal:
+			fmt.Fprintf(&sb, "  %s([%s]):::external\n", id, label)
+		}
+		if n.Changed {
+			changed = append(changed, id)
+		} else if n.Affected {
+			affected = append(affected, id)
+		}
+	}
+	for _, e := range g.Edges {
+		switch e.Kind {
+		case tarm.EdgeModuleCall:
+			fmt.Fprintf(&sb, "  %s --> %s\n", ids[e.From], ids[e.To])
+		default:
+			fmt.Fprintf(&sb, "  %s -. %s .-> %s\n", ids[e.From], e.Kind, ids[e.To])
+		}
+	}
+	sb.WriteString("  classDef root fill:#dbeafe,stroke:#1d4ed8,stroke-width:2px\n")
+	sb.WriteString("  classDef module fill:#f3f4f6,stroke:#6b7280\n")
+	sb.WriteString("  classDef file fill:#ffffff,stroke:#9ca3af\n")
+	sb.WriteString("  classDef external fill:#ffffff,stroke:#9ca3af,stroke-dasharray:4 3\n")
+	sb.WriteString("  classDef affected fill:#fef3c7,stroke:#d97706\n")
+	sb.WriteString("  classDef changed fill:#fee2e2,stroke:#dc2626,stroke-width:2px\n")
+	if len(affected) > 0 {
+		fmt.Fprintf(&sb, "  class %s affected\n", strings.Join(affected, ","))
+	}
+	if len(changed) > 0 {
+		fmt.Fprintf(&sb, "  class %s changed\n", strings.Join(changed, ","))
+	}
+
+	return sb.String()
+}
+
+// dotQuote quotes a DOT identifier.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// mermaidQuote quotes a Mermaid node label.
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
+
+// DefaultMaxFanOut is the number of root modules with the same dependencies in a diagram
+// above which they are collapsed into a single node.
+const DefaultMaxFanOut = 5
+
+// reasonEdgeKinds are the kinds of the edges drawn from a root module to a changed path affecting
+// it without a dependency chain. Reasons the dependency graph has no edge kind for, such as
+// inherited files, keep their own name.
+var reasonEdgeKinds = map[tarm.Reason]tarm.EdgeKind{
+	tarm.ReasonModule:        tarm.EdgeModuleCall,
+	tarm.ReasonFileReference: tarm.EdgeFileReference,
+	tarm.ReasonRemoteState:   tarm.EdgeRemoteState,
+	tarm.ReasonDependency:    tarm.EdgeDependency,
+	tarm.ReasonVarFile:       "var_file",
+	tarm.ReasonInherited:     "inherited",
+	tarm.ReasonParseError:    "parse_error",
+	tarm.ReasonGlobalTrigger: "global_trigger",
+}
+
+// AffectedGraph returns the subgraph leading from the affected root modules to the changed paths,
+// built from their dependency chains. Root modules with the same edges are collapsed into a single
+// node when there are more than maxFanOut of them; zero selects DefaultMaxFanOut. The labels of the
+// collapsed nodes, which break the line for Mermaid, are returned by node ID.
+func AffectedGraph(modules []tarm.AffectedRootModule, maxFanOut int) (*tarm.GraphDocument, map[string]string) {
+	if maxFanOut <= 0 {
+		maxFanOut = DefaultMaxFanOut
+	}
+
+	nodes := make(map[string]*tarm.GraphNode)
+	addNode := func(id string, kind tarm.NodeKind) *tarm.GraphNode {
+		if n, ok := nodes[id]; ok {
+			return n
+		}
+		nodes[id] = &tarm.GraphNode{ID: id, Kind: kind, Affected: true}
+		return nodes[id]
+	}
+	edges := make(map[[2]string]tarm.EdgeKind)
+
+	for _, m := range modules {
+		addNode(m.Path, tarm.NodeRoot)
+	}
+	for _, m := range modules {
+		for _, cause := range tarm.Unique(m.AffectedBy) {
+			if cause == m.Path {
+				nodes[m.Path].Changed = true
+				continue
+			}
+			chains := m.ChainsFor(cause)
+			if len(chains) == 0 {
+				// Inherited files, var files and the like affect the root module directly.
+				addNode(cause, tarm.NodeFile).Changed = true
+				edges[[2]string{m.Path, cause}] = reasonEdgeKinds[m.ReasonFor(cause)]
+				continue
+			}
+			for _, chain := range chains {
+				for _, step := range chain.Steps {
+					kind := tarm.NodeModule
+					if step.Kind == tarm.EdgeFileReference {
+						kind = tarm.NodeFile
+					}
+					addNode(step.To, kind)
+					edges[[2]string{step.From, step.To}] = step.Kind
+				}
+				nodes[chain.Cause].Changed = true
+			}
+		}
+	}
+
+	// Group root modules that nothing in the diagram depends on by their edges.
+	hasDependents := make(map[string]bool)
+	outgoing := make(map[string][]string)
+	for edge, kind := range edges {
+		hasDependents[edge[1]] = true
+		outgoing[edge[0]] = append(outgoing[edge[0]], edge[1]+" "+string(kind))
+	}
+	groups := make(map[string][]string)
+	for _, m := range modules {
+		if hasDependents[m.Path] {
+			continue
+		}
+		sort.Strings(outgoing[m.Path])
+		key := fmt.Sprintf("%t %s", nodes[m.Path].Changed, strings.Join(outgoing[m.Path], ","))
+		groups[key] = append(groups[key], m.Path)
+	}
+
+	collapsed := make(map[string]string)
+	labels := make(map[string]string)
+	for _, group := range groups {
+		if len(group) <= maxFanOut {
+			continue
+		}
+		sort.Strings(group)
+		id := fmt.Sprintf("%s and %d more root modules", group[0], len(group)-1)
+		labels[id] = fmt.Sprintf("%s<br/>and %d more root modules", group[0], len(group)-1)
+		n := addNode(id, tarm.NodeRoot)
+		n.Changed = nodes[group[0]].Changed
+		for _, root := range group {
+			collapsed[root] = id
+			delete(nodes, root)
+		}
+	}
+
+	doc := &tarm.GraphDocument{Version: tarm.GraphDocumentVersion, Nodes: []tarm.GraphNode{}, Edges: []tarm.GraphEdge{}}
+	for _, n := range nodes {
+		doc.Nodes = append(doc.Nodes, *n)
+	}
+	seen := make(map[[2]string]bool)
+	for edge, kind := range edges {
+		if id, ok := collapsed[edge[0]]; ok {
+			edge[0] = id
+		}
+		if !seen[edge] {
+			seen[edge] = true
+			doc.Edges = append(doc.Edges, tarm.GraphEdge{From: edge[0], To: edge[1], Kind: kind})
+		}
+	}
+	sort.Slice(doc.Nodes, func(i, j int) bool {
+		return doc.Nodes[i].ID < doc.Nodes[j].ID
+	})
+	sort.Slice(doc.Edges, func(i, j int) bool {
+		if doc.Edges[i].From != doc.Edges[j].From {
+			return doc.Edges[i].From < doc.Edges[j].From
+		}
+		return doc.Edges[i].To < doc.Edges[j].To
+	})
+	return doc, labels
+}
@@ -0,0 +1,148 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kzmshx/tarm/pkg/tarm"
+)
+
+var testGraph = &tarm.GraphDocument{
+	Version: tarm.GraphDocumentVersion,
+	Nodes: []tarm.GraphNode{
+		{ID: "envs/api", Kind: tarm.NodeRoot, Affected: true},
+		{ID: "envs/web", Kind: tarm.NodeRoot},
+		{ID: "hashicorp/consul/aws", Kind: tarm.NodeExternal},
+		{ID: "modules/db", Kind: tarm.NodeModule, Changed: true, Affected: true},
+	},
+	Edges: []tarm.GraphEdge{
+		{From: "envs/api", To: "hashicorp/consul/aws", Kind: tarm.EdgeModuleCall},
+		{From: "envs/api", To: "modules/db", Kind: tarm.EdgeModuleCall},
+		{From: "envs/web", To: "envs/api", Kind: tarm.EdgeRemoteState},
+	},
+}
+
+func TestDOT(t *testing.T) {
+	got := DOT(testGraph)
+
+	for _, want := range []string{
+		"digraph tarm {\n",
+		`  "envs/api" [shape=box, style="filled,bold", fillcolor="#fde68a"];`,
+		`  "hashicorp/consul/aws" [shape=ellipse, style="dashed"];`,
+		`  "modules/db" [shape=box, style="filled,rounded", fillcolor="#fef3c7", color="#dc2626", penwidth=2];`,
+		`  "envs/api" -> "modules/db";`,
+		`  "envs/web" -> "envs/api" [style=dashed, label="remote_state"];`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("DOT() missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	got := Mermaid(testGraph)
+
+	for _, want := range []string{
+		"flowchart LR\n",
+		`  n0["envs/api"]:::root`,
+		`  n2(["hashicorp/consul/aws"]):::external`,
+		`  n3("modules/db"):::module`,
+		"  n0 --> n3\n",
+		"  n1 -. remote_state .-> n0\n",
+		"  class n0 affected\n",
+		"  class n3 changed\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Mermaid() missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestAffectedGraph(t *testing.T) {
+	viaCommon := func(root string) tarm.AffectedRootModule {
+		return tarm.AffectedRootModule{
+			Path:       root,
+			AffectedBy: []string{"modules/common"},
+			Chains: []tarm.Chain{{Cause: "modules/common", Steps: []tarm.ChainStep{
+				{From: root, To: "modules/common", Kind: tarm.EdgeModuleCall},
+			}}},
+		}
+	}
+	modules := []tarm.AffectedRootModule{
+		viaCommon("envs/a"),
+		viaCommon("envs/b"),
+		viaCommon("envs/c"),
+		{
+			Path:       "envs/d",
+			AffectedBy: []string{"modules/common"},
+			Chains: []tarm.Chain{{Cause: "modules/common", Steps: []tarm.ChainStep{
+				{From: "envs/d", To: "modules/db", Kind: tarm.EdgeModuleCall},
+				{From: "modules/db", To: "modules/common", Kind: tarm.EdgeModuleCall},
+			}}},
+		},
+		{
+			Path:       "envs/e",
+			AffectedBy: []string{"envs/backend.hcl"},
+			Causes:     []tarm.Cause{{Path: "envs/backend.hcl", Reason: tarm.ReasonInherited}},
+		},
+		{Path: "envs/f", AffectedBy: []string{"envs/f"}},
+	}
+
+	tests := []struct {
+		name       string
+		maxFanOut  int
+		wantNodes  []string
+		wantEdges  []string
+		wantLabels map[string]string
+	}{
+		{
+			name:      "no collapsing",
+			maxFanOut: 3,
+			wantNodes: []string{"envs/a:root", "envs/b:root", "envs/backend.hcl:file:changed", "envs/c:root", "envs/d:root", "envs/e:root", "envs/f:root:changed", "modules/common:module:changed", "modules/db:module"},
+			wantEdges: []string{"envs/a->modules/common:module", "envs/b->modules/common:module", "envs/c->modules/common:module", "envs/d->modules/db:module", "envs/e->envs/backend.hcl:inherited", "modules/db->modules/common:module"},
+		},
+		{
+			name:       "fan-out is collapsed",
+			maxFanOut:  2,
+			wantNodes:  []string{"envs/a and 2 more root modules:root", "envs/backend.hcl:file:changed", "envs/d:root", "envs/e:root", "envs/f:root:changed", "modules/common:module:changed", "modules/db:module"},
+			wantEdges:  []string{"envs/a and 2 more root modules->modules/common:module", "envs/d->modules/db:module", "envs/e->envs/backend.hcl:inherited", "modules/db->modules/common:module"},
+			wantLabels: map[string]string{"envs/a and 2 more root modules": "envs/a<br/>and 2 more root modules"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, labels := AffectedGraph(modules, tt.maxFanOut)
+
+			var gotNodes, gotEdges []string
+			for _, n := range g.Nodes {
+				s := n.ID + ":" + string(n.Kind)
+				if n.Changed {
+					s += ":changed"
+				}
+				gotNodes = append(gotNodes, s)
+			}
+			for _, e := range g.Edges {
+				gotEdges = append(gotEdges, e.From+"->"+e.To+":"+string(e.Kind))
+			}
+			if strings.Join(gotNodes, "\n") != strings.Join(tt.wantNodes, "\n") {
+				t.Errorf("nodes = %q, want %q", gotNodes, tt.wantNodes)
+			}
+			if strings.Join(gotEdges, "\n") != strings.Join(tt.wantEdges, "\n") {
+				t.Errorf("edges = %q, want %q", gotEdges, tt.wantEdges)
+			}
+			for id, want := range tt.wantLabels {
+				if labels[id] != want {
+					t.Errorf("labels[%q] = %q, want %q", id, labels[id], want)
+				}
+				if got := mermaid(g, labels); !strings.Contains(got, fmt.Sprintf("[%s]", mermaidQuote(want))) {
+					t.Errorf("mermaid() missing label %q in:\n%s", want, got)
+				}
+			}
+			if len(labels) != len(tt.wantLabels) {
+				t.Errorf("labels = %q, want %q", labels, tt.wantLabels)
+			}
+		})
+	}
+}
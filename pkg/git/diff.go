@@ -0,0 +1,363 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ChangedFilesProvider detects changed files between two refs.
+type ChangedFilesProvider interface {
+	ChangedFiles(ctx context.Context) ([]string, error)
+}
+
+// Status is the kind of change made to a file.
+type Status string
+
+const (
+	StatusAdded    Status = "added"
+	StatusModified Status = "modified"
+	StatusDeleted  Status = "deleted"
+	StatusRenamed  Status = "renamed"
+)
+
+// FileChange is a changed file together with its change status.
+type FileChange struct {
+	Status Status `json:"status"`
+	// Path is the path after the change. For deleted files it is the removed path.
+	Path string `json:"path"`
+	// OldPath is the path before the change for renamed files.
+	OldPath string `json:"old_path,omitempty"`
+}
+
+// ChangesProvider detects changed files along with their change status.
+type ChangesProvider interface {
+	Changes(ctx context.Context) ([]FileChange, error)
+}
+
+// BaseTreeProvider lists the files present at the base of a comparison,
+// which allows deleted paths to be mapped to the modules they belonged to.
+type BaseTreeProvider interface {
+	BaseTree(ctx context.Context) ([]string, error)
+}
+
+// RepositoryProvider reports paths relative to the top level of a git repository.
+type RepositoryProvider interface {
+	// TopLevel returns the absolute path of the top level of the repository, or an empty
+	// string if the paths are not relative to a repository after all.
+	TopLevel(ctx context.Context) (string, error)
+}
+
+// TopLevel returns the absolute path of the top level of the git repository containing dir.
+// An empty dir selects the current directory.
+func TopLevel(ctx context.Context, dir string) (string, error) {
+	output, err := command(ctx, dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse --show-toplevel failed: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// command returns a git command running in dir, or in the current directory if dir is empty.
+func command(ctx context.Context, dir string, args ...string) *exec.Cmd {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	return exec.CommandContext(ctx, "git", args...)
+}
+
+// RefResolver resolves the refs of a comparison to commit SHAs.
+type RefResolver interface {
+	ResolveRefs(ctx context.Context) (base, head string, err error)
+}
+
+// Changes returns the changes detected by p. Providers that do not report a change
+// status have all of their files reported as modified.
+func Changes(ctx context.Context, p ChangedFilesProvider) ([]FileChange, error) {
+	if cp, ok := p.(ChangesProvider); ok {
+		return cp.Changes(ctx)
+	}
+
+	files, err := p.ChangedFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]FileChange, 0, len(files))
+	for _, f := range files {
+		changes = append(changes, FileChange{Status: StatusModified, Path: f})
+	}
+	return changes, nil
+}
+
+// ChangedPaths returns every path touched by the changes detected by p,
+// including both the old and new paths of renamed files.
+func ChangedPaths(ctx context.Context, p ChangedFilesProvider) ([]string, error) {
+	changes, err := Changes(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, c := range changes {
+		if c.OldPath != "" {
+			paths = append(paths, c.OldPath)
+		}
+		paths = append(paths, c.Path)
+	}
+	return paths, nil
+}
+
+// DiffProvider detects changed files using git diff. Paths are relative to the top level
+// of the repository.
+type DiffProvider struct {
+	BaseRef string
+	HeadRef string
+	// Dir is a directory in the repository git runs in; empty selects the current directory.
+	Dir string
+}
+
+// ChangedFiles returns the list of files changed between BaseRef and HeadRef.
+func (p *DiffProvider) ChangedFiles(ctx context.Context) ([]string, error) {
+	args := buildDiffArgs(p.BaseRef, p.HeadRef)
+	cmd := command(ctx, p.Dir, args...)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w", err)
+	}
+
+	return parseLines(string(output)), nil
+}
+
+// Changes returns the files changed between BaseRef and HeadRef with their change status.
+func (p *DiffProvider) Changes(ctx context.Context) ([]FileChange, error) {
+	args := diffArgs([]string{"--name-status", "--find-renames"}, p.BaseRef, p.HeadRef)
+	cmd := command(ctx, p.Dir, args...)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w", err)
+	}
+
+	return parseNameStatus(string(output)), nil
+}
+
+// BaseTree returns the files present at the base of the comparison: BaseRef, or the merge base
+// of BaseRef and HeadRef when the changes are those HeadRef made since it diverged from BaseRef.
+func (p *DiffProvider) BaseTree(ctx context.Context) ([]string, error) {
+	base := p.BaseRef
+	if p.HeadRef != "" && p.HeadRef != "HEAD" {
+		var err error
+		if base, err = mergeBase(ctx, p.Dir, p.BaseRef, p.HeadRef); err != nil {
+			return nil, err
+		}
+	}
+	cmd := command(ctx, p.Dir, "ls-tree", "-r", "--full-tree", "--name-only", base)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-tree failed: %w", err)
+	}
+
+	return parseLines(string(output)), nil
+}
+
+// TopLevel returns the top level of the repository containing Dir.
+func (p *DiffProvider) TopLevel(ctx context.Context) (string, error) {
+	return TopLevel(ctx, p.Dir)
+}
+
+// ResolveRefs returns the commit SHAs BaseRef and HeadRef point to.
+func (p *DiffProvider) ResolveRefs(ctx context.Context) (string, string, error) {
+	headRef := p.HeadRef
+	if headRef == "" {
+		headRef = "HEAD"
+	}
+	base, err := revParse(ctx, p.Dir, p.BaseRef)
+	if err != nil {
+		return "", "", err
+	}
+	head, err := revParse(ctx, p.Dir, headRef)
+	if err != nil {
+		return "", "", err
+	}
+	return base, head, nil
+}
+
+// revParse returns the SHA of the commit ref points to.
+func revParse(ctx context.Context, dir, ref string) (string, error) {
+	cmd := command(ctx, dir, "rev-parse", "--verify", "--end-of-options", ref+"^{commit}")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse %s failed: %w", ref, err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// mergeBase returns the SHA of the best common ancestor of the commits a and b point to.
+func mergeBase(ctx context.Context, dir, a, b string) (string, error) {
+	cmd := command(ctx, dir, "merge-base", "--end-of-options", a, b)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git merge-base %s %s failed: %w", a, b, err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// buildDiffArgs constructs git diff arguments from base and head refs.
+func buildDiffArgs(baseRef, headRef string) []string {
+	return diffArgs([]string{"--name-only"}, baseRef, headRef)
+}
+
+func diffArgs(options []string, baseRef, headRef string) []string {
+	args := append([]string{"diff"}, options...)
+	if headRef == "" || headRef == "HEAD" {
+		return append(args, baseRef, "HEAD")
+	}
+	return append(args, fmt.Sprintf("%s...%s", baseRef, headRef))
+}
+
+// parseNameStatus parses the output of git diff --name-status.
+func parseNameStatus(s string) []FileChange {
+	var changes []FileChange
+	for _, line := range parseLines(s) {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+
+		switch fields[0][0] {
+		case 'A':
+			changes = append(changes, FileChange{Status: StatusAdded, Path: fields[1]})
+		case 'D':
+			changes = append(changes, FileChange{Status: StatusDeleted, Path: fields[1]})
+		case 'R':
+			if len(fields) < 3 {
+				continue
+			}
+			changes = append(changes, FileChange{Status: StatusRenamed, Path: fields[2], OldPath: fields[1]})
+		case 'C':
+			if len(fields) < 3 {
+				continue
+			}
+			changes = append(changes, FileChange{Status: StatusAdded, Path: fields[2]})
+		default:
+			changes = append(changes, FileChange{Status: StatusModified, Path: fields[1]})
+		}
+	}
+	return changes
+}
+
+// parseLines returns the non-empty lines of git output, trimmed.
+func parseLines(s string) []string {
+	var result []string
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// StaticProvider returns a fixed list of changed files.
+type StaticProvider struct {
+	Files []string
+}
+
+// ChangedFiles returns the static file list.
+func (p *StaticProvider) ChangedFiles(ctx context.Context) ([]string, error) {
+	return p.Files, nil
+}
+
+// Changes returns the static file list, with every file reported as modified.
+func (p *StaticProvider) Changes(ctx context.Context) ([]FileChange, error) {
+	changes := make([]FileChange, 0, len(p.Files))
+	for _, f := range p.Files {
+		changes = append(changes, FileChange{Status: StatusModified, Path: f})
+	}
+	return changes, nil
+}
+
+// MultiProvider combines multiple providers, deduplicating results.
+type MultiProvider struct {
+	Providers []ChangedFilesProvider
+}
+
+// ChangedFiles collects files from all providers and deduplicates.
+func (p *MultiProvider) ChangedFiles(ctx context.Context) ([]string, error) {
+	seen := make(map[string]bool)
+	var all []string
+	for _, provider := range p.Providers {
+		files, err := provider.ChangedFiles(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if !seen[f] {
+				seen[f] = true
+				all = append(all, f)
+			}
+		}
+	}
+	return all, nil
+}
+
+// Changes collects changes from all providers and deduplicates them.
+func (p *MultiProvider) Changes(ctx context.Context) ([]FileChange, error) {
+	seen := make(map[FileChange]bool)
+	var all []FileChange
+	for _, provider := range p.Providers {
+		changes, err := Changes(ctx, provider)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range changes {
+			if !seen[c] {
+				seen[c] = true
+				all = append(all, c)
+			}
+		}
+	}
+	return all, nil
+}
+
+// BaseTree returns the base tree of the first provider that can list one.
+func (p *MultiProvider) BaseTree(ctx context.Context) ([]string, error) {
+	for _, provider := range p.Providers {
+		if tp, ok := provider.(BaseTreeProvider); ok {
+			return tp.BaseTree(ctx)
+		}
+	}
+	return nil, nil
+}
+
+// TopLevel returns the top level of the repository of the first provider reporting paths relative
+// to one, or an empty string if no provider does. The paths of every provider are rebased against
+// it, so the paths of a StaticProvider combined with a DiffProvider must be relative to the top
+// level as well.
+func (p *MultiProvider) TopLevel(ctx context.Context) (string, error) {
+	for _, provider := range p.Providers {
+		if rp, ok := provider.(RepositoryProvider); ok {
+			return rp.TopLevel(ctx)
+		}
+	}
+	return "", nil
+}
+
+// ResolveRefs resolves the refs of the first provider that can resolve them.
+func (p *MultiProvider) ResolveRefs(ctx context.Context) (string, string, error) {
+	for _, provider := range p.Providers {
+		if rp, ok := provider.(RefResolver); ok {
+			return rp.ResolveRefs(ctx)
+		}
+	}
+	return "", "", nil
+}
@@ -0,0 +1,330 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBuildDiffArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseRef string
+		headRef string
+		want    []string
+	}{
+		{
+			name:    "headRef empty uses HEAD",
+			baseRef: "origin/main",
+			headRef: "",
+			want:    []string{"diff", "--name-only", "origin/main", "HEAD"},
+		},
+		{
+			name:    "headRef HEAD uses two-arg form",
+			baseRef: "origin/main",
+			headRef: "HEAD",
+			want:    []string{"diff", "--name-only", "origin/main", "HEAD"},
+		},
+		{
+			name:    "explicit headRef uses three-dot form",
+			baseRef: "origin/main",
+			headRef: "origin/feature",
+			want:    []string{"diff", "--name-only", "origin/main...origin/feature"},
+		},
+		{
+			name:    "bare branch names without origin prefix",
+			baseRef: "main",
+			headRef: "feature",
+			want:    []string{"diff", "--name-only", "main...feature"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildDiffArgs(tt.baseRef, tt.headRef)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestStaticProvider(t *testing.T) {
+	p := &StaticProvider{Files: []string{"a.tf", "b.tf"}}
+	files, err := p.ChangedFiles(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0] != "a.tf" || files[1] != "b.tf" {
+		t.Errorf("got %v, want [a.tf b.tf]", files)
+	}
+}
+
+func TestStaticProvider_Empty(t *testing.T) {
+	p := &StaticProvider{}
+	files, err := p.ChangedFiles(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("got %v, want empty", files)
+	}
+}
+
+func TestMultiProvider(t *testing.T) {
+	p := &MultiProvider{
+		Providers: []ChangedFilesProvider{
+			&StaticProvider{Files: []string{"a.tf", "b.tf"}},
+			&StaticProvider{Files: []string{"b.tf", "c.tf"}},
+		},
+	}
+	files, err := p.ChangedFiles(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("got %v (len=%d), want 3 unique files", files, len(files))
+	}
+}
+
+func TestMultiProvider_Empty(t *testing.T) {
+	p := &MultiProvider{}
+	files, err := p.ChangedFiles(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("got %v, want empty", files)
+	}
+	if topLevel, err := p.TopLevel(context.Background()); err != nil || topLevel != "" {
+		t.Errorf("TopLevel() = %q, %v, want empty", topLevel, err)
+	}
+}
+
+func TestMultiProvider_TopLevel(t *testing.T) {
+	dir, _, _ := newTestRepo(t)
+	// The static paths are rebased against the top level of the repository as if git reported them.
+	p := &MultiProvider{Providers: []ChangedFilesProvider{&StaticProvider{}, &DiffProvider{Dir: dir}}}
+
+	topLevel, err := p.TopLevel(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := filepath.EvalSymlinks(dir); topLevel != want {
+		t.Errorf("TopLevel() = %s, want %s", topLevel, want)
+	}
+}
+
+func TestParseNameStatus(t *testing.T) {
+	output := "M\tmodules/network/main.tf\n" +
+		"A\tmodules/new/main.tf\n" +
+		"D\tenvironments/dev/legacy/main.tf\n" +
+		"R087\tmodules/old/main.tf\tmodules/renamed/main.tf\n" +
+		"C100\tmodules/a/main.tf\tmodules/b/main.tf\n" +
+		"T\tscripts/run.sh\n"
+
+	want := []FileChange{
+		{Status: StatusModified, Path: "modules/network/main.tf"},
+		{Status: StatusAdded, Path: "modules/new/main.tf"},
+		{Status: StatusDeleted, Path: "environments/dev/legacy/main.tf"},
+		{Status: StatusRenamed, Path: "modules/renamed/main.tf", OldPath: "modules/old/main.tf"},
+		{Status: StatusAdded, Path: "modules/b/main.tf"},
+		{Status: StatusModified, Path: "scripts/run.sh"},
+	}
+
+	got := parseNameStatus(output)
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("change %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDiffArgs_NameStatus(t *testing.T) {
+	got := diffArgs([]string{"--name-status", "--find-renames"}, "origin/main", "origin/feature")
+	want := []string{"diff", "--name-status", "--find-renames", "origin/main...origin/feature"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+type renameProvider struct{}
+
+func (renameProvider) ChangedFiles(context.Context) ([]string, error) {
+	return []string{"b.tf"}, nil
+}
+
+func (renameProvider) Changes(context.Context) ([]FileChange, error) {
+	return []FileChange{{Status: StatusRenamed, Path: "b.tf", OldPath: "a.tf"}}, nil
+}
+
+func TestChangedPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider ChangedFilesProvider
+		want     []string
+	}{
+		{name: "renames include old and new paths", provider: renameProvider{}, want: []string{"a.tf", "b.tf"}},
+		{name: "static provider files", provider: &StaticProvider{Files: []string{"x.tf"}}, want: []string{"x.tf"}},
+		{
+			name:     "multi provider combines providers",
+			provider: &MultiProvider{Providers: []ChangedFilesProvider{renameProvider{}, &StaticProvider{Files: []string{"b.tf", "c.tf"}}}},
+			want:     []string{"a.tf", "b.tf", "b.tf", "c.tf"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ChangedPaths(context.Background(), tt.provider)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// newTestRepo creates an empty repository, returning its directory and functions running git
+// in it and writing files to it.
+func newTestRepo(t *testing.T) (dir string, gitCmd func(args ...string) string, writeFile func(name, content string)) {
+	t.Helper()
+	dir = t.TempDir()
+	gitCmd = func(args ...string) string {
+		t.Helper()
+		args = append([]string{"-C", dir, "-c", "user.name=tarm", "-c", "user.email=tarm@example.com"}, args...)
+		out, err := exec.Command("git", args...).Output()
+		if err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	writeFile = func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gitCmd("init", "-q")
+	return dir, gitCmd, writeFile
+}
+
+func TestDiffProvider(t *testing.T) {
+	dir, gitCmd, writeFile := newTestRepo(t)
+	writeFile("infra/modules/a/main.tf", "# a\n")
+	gitCmd("add", "-A")
+	gitCmd("commit", "-q", "-m", "base")
+	gitCmd("tag", "base")
+	writeFile("infra/modules/a/main.tf", "# a changed\n")
+	writeFile("infra/modules/b/main.tf", "# b\n")
+	gitCmd("add", "-A")
+	gitCmd("commit", "-q", "-m", "head")
+	wantBase, wantHead := gitCmd("rev-parse", "base"), gitCmd("rev-parse", "HEAD")
+
+	// git runs in a subdirectory of the repository, not in the current directory.
+	p := &DiffProvider{BaseRef: "base", Dir: filepath.Join(dir, "infra", "modules")}
+
+	changed, err := p.ChangedFiles(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"infra/modules/a/main.tf", "infra/modules/b/main.tf"}; !slices.Equal(changed, want) {
+		t.Errorf("ChangedFiles() = %v, want %v", changed, want)
+	}
+
+	tree, err := p.BaseTree(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"infra/modules/a/main.tf"}; !slices.Equal(tree, want) {
+		t.Errorf("BaseTree() = %v, want %v", tree, want)
+	}
+
+	topLevel, err := p.TopLevel(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := filepath.EvalSymlinks(dir); topLevel != want {
+		t.Errorf("TopLevel() = %s, want %s", topLevel, want)
+	}
+
+	base, head, err := p.ResolveRefs(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base != wantBase || head != wantHead {
+		t.Errorf("ResolveRefs() = %s, %s, want %s, %s", base, head, wantBase, wantHead)
+	}
+
+	if _, _, err := (&DiffProvider{BaseRef: "missing", Dir: dir}).ResolveRefs(t.Context()); err == nil {
+		t.Error("expected error for a missing ref")
+	}
+}
+
+func TestDiffProvider_BaseTreeAtMergeBase(t *testing.T) {
+	dir, gitCmd, writeFile := newTestRepo(t)
+	writeFile("modules/a/main.tf", "# a\n")
+	gitCmd("add", "-A")
+	gitCmd("commit", "-q", "-m", "base")
+	gitCmd("branch", "-M", "main")
+	gitCmd("checkout", "-q", "-b", "feature")
+	writeFile("modules/a/main.tf", "# a changed\n")
+	gitCmd("commit", "-q", "-am", "feature")
+	gitCmd("checkout", "-q", "main")
+	writeFile("modules/b/main.tf", "# b\n")
+	gitCmd("add", "-A")
+	gitCmd("commit", "-q", "-m", "main")
+
+	tests := []struct {
+		name     string
+		provider *DiffProvider
+		want     []string
+	}{
+		{name: "three-dot diff lists the merge base", provider: &DiffProvider{BaseRef: "main", HeadRef: "feature", Dir: dir}, want: []string{"modules/a/main.tf"}},
+		{name: "diff against HEAD lists the base ref", provider: &DiffProvider{BaseRef: "main", Dir: dir}, want: []string{"modules/a/main.tf", "modules/b/main.tf"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := tt.provider.BaseTree(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(tree, tt.want) {
+				t.Errorf("BaseTree() = %v, want %v", tree, tt.want)
+			}
+		})
+	}
+
+	if _, err := (&DiffProvider{BaseRef: "main", HeadRef: "missing", Dir: dir}).BaseTree(t.Context()); err == nil {
+		t.Error("expected error for a missing ref")
+	}
+}
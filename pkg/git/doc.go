@@ -0,0 +1,2 @@
+// Package git detects the files changed between two git refs for tarm.
+package git
@@ -0,0 +1,1081 @@
+package tarm
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+)
+
+// Analyzer analyzes Terraform module dependencies.
+type Analyzer struct {
+	root        string
+	graph       *DependencyGraph
+	diagnostics []Diagnostic
+
+	// modules are the module directories found during analysis.
+	modules []string
+
+	// backends maps state keys to the modules that store their state there, in the order found.
+	backends map[string][]string
+	// remoteStates maps modules to the states they read via terraform_remote_state.
+	remoteStates map[string][]StateLocation
+	// rootReferences maps modules to the paths they reference relative to path.root or path.cwd.
+	rootReferences map[string][]string
+
+	// units are the directories containing a terragrunt.hcl.
+	units []string
+	// includedUnits are units whose terragrunt.hcl is included by another unit.
+	includedUnits map[string]bool
+
+	// baseModules are the module directories that existed at the base of the comparison.
+	baseModules map[string]bool
+
+	// tofu enables OpenTofu file handling.
+	tofu bool
+
+	// inheritedFilePatterns match files that affect every root module beneath their directory.
+	inheritedFilePatterns []string
+
+	// globalTriggers match files that affect root modules regardless of their dependencies.
+	globalTriggers []GlobalTrigger
+
+	// sourceRewrites map remote module sources to local directories.
+	sourceRewrites []SourceRewrite
+
+	// parseErrors are the modules whose configuration failed to parse.
+	parseErrors []string
+	// parseErrorPolicy decides how modules that failed to parse are handled.
+	parseErrorPolicy ParseErrorPolicy
+
+	// concurrency is the number of modules parsed at once; zero selects GOMAXPROCS.
+	concurrency int
+
+	// cacheDir is the directory parsed modules are cached in, or empty to disable the cache.
+	cacheDir string
+	cache    *moduleCache
+
+	// diagnosticHandler is called with every diagnostic as it is reported.
+	diagnosticHandler func(Diagnostic)
+
+	// mappedChanges memoizes mapChange, so that a path is mapped and diagnosed only once.
+	mappedChanges map[string]mappedChange
+}
+
+// ParseErrorPolicy decides how modules whose configuration fails to parse are handled.
+// Their module calls are always recovered by a fallback scanner where possible.
+type ParseErrorPolicy string
+
+const (
+	// ParseErrorSkip reports a warning and keeps the module calls found by the fallback scanner.
+	ParseErrorSkip ParseErrorPolicy = "skip"
+	// ParseErrorTreatAsAffected additionally treats every module that failed to parse as changed.
+	ParseErrorTreatAsAffected ParseErrorPolicy = "treat-as-affected"
+	// ParseErrorFail makes the analysis fail.
+	ParseErrorFail ParseErrorPolicy = "fail"
+)
+
+// ParseParseErrorPolicy parses a policy name. An empty name selects ParseErrorSkip.
+func ParseParseErrorPolicy(s string) (ParseErrorPolicy, error) {
+	switch policy := ParseErrorPolicy(s); policy {
+	case "":
+		return ParseErrorSkip, nil
+	case ParseErrorSkip, ParseErrorTreatAsAffected, ParseErrorFail:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid parse error policy %q: must be one of skip, treat-as-affected, fail", s)
+	}
+}
+
+// AnalyzerOption configures an Analyzer.
+type AnalyzerOption func(*Analyzer)
+
+// WithBaseTree sets the files, relative to the root directory, that existed at the base
+// of the comparison. It is used to map deleted paths to the modules they belonged to.
+func WithBaseTree(files []string) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.baseModules = make(map[string]bool)
+		for _, f := range files {
+			if isModuleFile(filepath.Base(f)) {
+				a.baseModules[filepath.Dir(filepath.Clean(f))] = true
+			}
+		}
+	}
+}
+
+// WithOpenTofu makes the analyzer load configuration as OpenTofu does: .tofu and .tofu.json
+// files are read, and shadow the .tf and .tf.json files with the same name.
+func WithOpenTofu() AnalyzerOption {
+	return func(a *Analyzer) {
+		a.tofu = true
+	}
+}
+
+// WithInheritedFiles sets glob patterns for files that are inherited by every root module
+// beneath their directory, such as backend.hcl, common.tfvars or .terraform-version.
+// Patterns without a slash match the file name; others match the path relative to the root directory.
+func WithInheritedFiles(patterns []string) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.inheritedFilePatterns = patterns
+	}
+}
+
+// WithGlobalTriggers sets the global triggers, which mark root modules as affected whenever a
+// matching file changes, regardless of the dependency graph.
+func WithGlobalTriggers(triggers []GlobalTrigger) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.globalTriggers = triggers
+	}
+}
+
+// WithSourceRewrites sets rules mapping remote module sources to local directories, so that
+// modules consumed from the repository's own git URL or registry namespace become dependencies.
+func WithSourceRewrites(rules []SourceRewrite) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.sourceRewrites = rules
+	}
+}
+
+// WithParseErrorPolicy sets how modules whose configuration fails to parse are handled.
+func WithParseErrorPolicy(policy ParseErrorPolicy) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.parseErrorPolicy = policy
+	}
+}
+
+// WithConcurrency sets the number of modules parsed at once. Zero or a negative number selects
+// runtime.GOMAXPROCS. The result does not depend on the concurrency.
+func WithConcurrency(n int) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.concurrency = n
+	}
+}
+
+// WithCache caches parsed modules in dir, so that only modules whose configuration files
+// changed since a previous analysis are parsed again.
+func WithCache(dir string) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.cacheDir = dir
+	}
+}
+
+// WithDiagnosticHandler sets a function called with every diagnostic as it is reported, e.g. to
+// log them while a long analysis runs. Diagnostics are also returned by Diagnostics.
+func WithDiagnosticHandler(handler func(Diagnostic)) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.diagnosticHandler = handler
+	}
+}
+
+// NewAnalyzer creates a new analyzer for the given root directory.
+func NewAnalyzer(root string, opts ...AnalyzerOption) *Analyzer {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		absRoot = root
+	}
+	a := &Analyzer{
+		root:           absRoot,
+		graph:          NewDependencyGraph(),
+		backends:       make(map[string][]string),
+		remoteStates:   make(map[string][]StateLocation),
+		rootReferences: make(map[string][]string),
+		includedUnits:  make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Analyze walks the root directory and builds a dependency graph. It stops early and returns
+// the context's error if ctx is canceled.
+func (a *Analyzer) Analyze(ctx context.Context) error {
+	if a.cacheDir != "" {
+		cache, err := openModuleCache(a.cacheDir)
+		if err != nil {
+			a.diagnose(Diagnostic{Code: DiagCacheError, Message: fmt.Sprintf("failed to open cache %s: %v", a.cacheDir, err)})
+		}
+		a.cache = cache
+	}
+	if err := a.walk(ctx); err != nil {
+		return err
+	}
+	a.linkRemoteStates()
+	a.linkRootReferences()
+
+	if a.parseErrorPolicy == ParseErrorFail && len(a.parseErrors) > 0 {
+		return fmt.Errorf("failed to parse %d module(s): %s", len(a.parseErrors), strings.Join(a.parseErrors, ", "))
+	}
+	return nil
+}
+
+// ParseErrors returns the modules whose configuration failed to parse, sorted.
+func (a *Analyzer) ParseErrors() []string {
+	modules := Unique(a.parseErrors)
+	sort.Strings(modules)
+	return modules
+}
+
+// walk finds the module directories under the root directory and parses them on a pool of
+// workers. The parsed modules are added to the graph in the order they were found, so warnings
+// and the graph do not depend on scheduling.
+func (a *Analyzer) walk(ctx context.Context) error {
+	var cacheDir string
+	if a.cacheDir != "" {
+		cacheDir, _ = filepath.Abs(a.cacheDir)
+	}
+
+	var dirs []string
+	err := filepath.WalkDir(a.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if d.IsDir() && (d.Name() == ".terraform" || strings.Contains(path, ".terragrunt-cache") || path == cacheDir) {
+			return filepath.SkipDir
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		hasTfFiles, err := containsTerraformFiles(path)
+		if err != nil {
+			return err
+		}
+		if hasTfFiles {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	parsed := make([]*parsedModule, len(dirs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(a.workers(), len(dirs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				parsed[i] = a.parseModule(dirs[i])
+			}
+		}()
+	}
+	for i := range dirs {
+		if ctx.Err() != nil {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range parsed {
+		if err := a.addModule(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// workers returns the number of modules parsed concurrently.
+func (a *Analyzer) workers() int {
+	if a.concurrency > 0 {
+		return a.concurrency
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// parsedModule is what parsing a module directory found. It is produced concurrently and
+// only touches the analyzer when added to it.
+type parsedModule struct {
+	path string
+
+	// hasUnit is set if the directory contains a terragrunt.hcl, loaded into unit or unitErr.
+	hasUnit bool
+	unit    *TerragruntUnit
+	unitErr error
+
+	moduleCalls map[string]*tfconfig.ModuleCall
+	// parseErr is the error tfconfig reported, and scanErr that of the fallback scanner.
+	parseErr error
+	scanErr  error
+
+	// bodiesErr is set if the native syntax files could not be read.
+	bodiesErr error
+	// references are the paths the module references that exist.
+	references []string
+	// rootReferences are the paths the module references relative to path.root or path.cwd.
+	rootReferences []string
+	backend        *StateLocation
+	remoteStates   []StateLocation
+}
+
+// parseModule parses the module in path, or loads it from the cache if its configuration files
+// have not changed. Terragrunt units are always loaded, since they may include files elsewhere.
+// Referenced paths are checked for existence on every run.
+func (a *Analyzer) parseModule(path string) *parsedModule {
+	var key string
+	var p *parsedModule
+	if a.cache != nil {
+		var err error
+		if key, err = a.cache.key(a.root, path, a.tofu); err == nil {
+			p, _ = a.cache.load(key, a.root, path)
+		}
+	}
+	if p == nil {
+		p = a.parseConfig(path)
+		if key != "" {
+			// The cache is best effort; a module that cannot be stored is parsed again next time.
+			_ = a.cache.store(key, a.root, p)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(path, TerragruntConfigFile)); err == nil {
+		p.hasUnit = true
+		p.unit, p.unitErr = LoadTerragruntUnit(path)
+	}
+
+	var refs []string
+	for _, ref := range p.references {
+		if _, err := os.Stat(ref); err == nil {
+			refs = append(refs, ref)
+		}
+	}
+	p.references = refs
+	return p
+}
+
+// parseConfig parses the configuration files of the module in path. Module calls are read from
+// the native syntax files directly when possible; tfconfig is only used for JSON and override
+// files and to diagnose files that fail to parse.
+func (a *Analyzer) parseConfig(path string) *parsedModule {
+	p := &parsedModule{path: path}
+
+	bodies, simple, bodiesErr := parseModuleFiles(path, a.tofu)
+	loaded := false
+	if bodiesErr == nil && simple {
+		p.moduleCalls, loaded = moduleCallsFromBodies(bodies)
+	}
+	if !loaded {
+		module, diags := loadModule(path, a.tofu)
+		if module != nil {
+			p.moduleCalls = module.ModuleCalls
+		}
+		if diags.HasErrors() {
+			p.parseErr = diags
+			// Recover the module calls of the broken files so their dependencies are not lost.
+			p.moduleCalls, p.scanErr = scanModuleCalls(path, a.tofu)
+			if p.scanErr != nil {
+				return p
+			}
+		}
+	}
+
+	if bodiesErr != nil {
+		p.bodiesErr = bodiesErr
+		return p
+	}
+	p.references, p.rootReferences = fileReferenceCandidates(path, bodies)
+	p.backend = findBackend(path, bodies)
+	p.remoteStates = findRemoteStates(path, bodies)
+	return p
+}
+
+// addModule adds a parsed module to the graph, reporting the warnings parsing it produced.
+func (a *Analyzer) addModule(p *parsedModule) error {
+	path := p.path
+	relPath, err := filepath.Rel(a.root, path)
+	if err != nil {
+		return err
+	}
+	a.modules = append(a.modules, relPath)
+
+	if p.hasUnit {
+		a.addTerragruntUnit(relPath, p.unit, p.unitErr)
+	}
+
+	if p.parseErr != nil {
+		file, line := diagnosticPosition(a.root, p.parseErr)
+		a.diagnose(Diagnostic{
+			Code:    DiagParseError,
+			Message: fmt.Sprintf("failed to parse %s: %s", relPath, p.parseErr),
+			File:    file,
+			Line:    line,
+			Module:  relPath,
+		})
+		a.parseErrors = append(a.parseErrors, relPath)
+
+		if p.scanErr != nil {
+			a.diagnose(Diagnostic{Code: DiagScanError, Message: fmt.Sprintf("failed to scan %s: %v", relPath, p.scanErr), Module: relPath})
+			return nil
+		}
+	}
+
+	names := make([]string, 0, len(p.moduleCalls))
+	for name := range p.moduleCalls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		call := p.moduleCalls[name]
+		moduleCall := ModuleCall{
+			Module:  relPath,
+			Name:    call.Name,
+			Source:  ParseModuleSource(call.Source),
+			Version: call.Version,
+			Line:    call.Pos.Line,
+		}
+		if file, err := filepath.Rel(a.root, call.Pos.Filename); err == nil {
+			moduleCall.File = file
+		}
+		moduleCall.Local = a.resolveModuleCall(path, moduleCall)
+		a.graph.AddModuleCall(moduleCall)
+
+		if moduleCall.Local != "" {
+			a.graph.AddDependency(relPath, moduleCall.Local)
+		}
+	}
+
+	if p.bodiesErr != nil {
+		a.diagnose(Diagnostic{Code: DiagReadError, Message: fmt.Sprintf("failed to read %s: %v", relPath, p.bodiesErr), Module: relPath})
+		return nil
+	}
+
+	for _, ref := range p.references {
+		relRef, err := filepath.Rel(a.root, ref)
+		if err != nil || !IsWithinDirectory(ref, a.root) {
+			continue
+		}
+		a.graph.AddDependencyKind(relPath, relRef, EdgeFileReference)
+	}
+	if len(p.rootReferences) > 0 {
+		a.rootReferences[relPath] = p.rootReferences
+	}
+
+	if p.backend != nil {
+		if key := p.backend.Key(); key != "" {
+			if producers := a.backends[key]; len(producers) > 0 {
+				a.diagnose(Diagnostic{
+					Code:    DiagDuplicateBackend,
+					Message: fmt.Sprintf("%s stores its state in the same %s backend as %s; remote state readers depend on all of them", relPath, p.backend.Backend, strings.Join(producers, ", ")),
+					Module:  relPath,
+				})
+			}
+			a.backends[key] = append(a.backends[key], relPath)
+		}
+	}
+	if len(p.remoteStates) > 0 {
+		a.remoteStates[relPath] = p.remoteStates
+	}
+
+	return nil
+}
+
+// resolveModuleCall returns the local module directory, relative to the root directory, that
+// a module call in the module at path resolves to, or an empty string for remote sources.
+func (a *Analyzer) resolveModuleCall(path string, call ModuleCall) string {
+	source, version := call.Source.Raw, call.Version
+	diagnose := func(code DiagnosticCode, format string, args ...any) {
+		a.diagnose(Diagnostic{
+			Code:    code,
+			Message: fmt.Sprintf(format, args...),
+			File:    call.File,
+			Line:    call.Line,
+			Module:  call.Module,
+		})
+	}
+
+	resolvedPath, err := ResolveModuleSource(path, source)
+	if err != nil {
+		diagnose(DiagInvalidModuleSource, "failed to resolve module source %q in %s: %v", source, call.Module, err)
+		return ""
+	}
+
+	if resolvedPath == "" {
+		resolvedPath = a.rewriteSource(source, version)
+	}
+	if resolvedPath == "" {
+		return ""
+	}
+
+	relResolvedPath, err := filepath.Rel(a.root, resolvedPath)
+	if err != nil {
+		diagnose(DiagPathError, "failed to get relative path for %s: %v", resolvedPath, err)
+		return ""
+	}
+
+	if _, err := os.Stat(resolvedPath); os.IsNotExist(err) {
+		diagnose(DiagModuleSourceNotFound, "module source %q not found in module %q", source, call.Module)
+		return ""
+	}
+
+	return relResolvedPath
+}
+
+// addTerragruntUnit adds edges from a loaded Terragrunt unit to its module source,
+// the units it depends on and the configuration files it includes.
+func (a *Analyzer) addTerragruntUnit(relPath string, unit *TerragruntUnit, err error) {
+	if err != nil {
+		file := filepath.Join(relPath, TerragruntConfigFile)
+		a.diagnose(Diagnostic{Code: DiagParseError, Message: fmt.Sprintf("failed to parse %s: %s", file, err), File: file, Module: relPath})
+		a.parseErrors = append(a.parseErrors, relPath)
+		return
+	}
+	a.units = append(a.units, relPath)
+
+	addEdge := func(target string, kind EdgeKind) {
+		if !IsWithinDirectory(target, a.root) {
+			return
+		}
+		relTarget, err := filepath.Rel(a.root, target)
+		if err != nil {
+			return
+		}
+		if _, err := os.Stat(target); os.IsNotExist(err) {
+			a.diagnose(Diagnostic{
+				Code:    DiagTerragruntPathNotFound,
+				Message: fmt.Sprintf("terragrunt path %q not found in unit %q", relTarget, relPath),
+				File:    filepath.Join(relPath, TerragruntConfigFile),
+				Module:  relPath,
+			})
+			return
+		}
+		a.graph.AddDependencyKind(relPath, relTarget, kind)
+	}
+
+	if unit.Source == "" && unit.RawSource != "" {
+		unit.Source = a.rewriteSource(unit.RawSource, "")
+	}
+	if unit.Source != "" {
+		addEdge(unit.Source, EdgeModuleCall)
+	}
+	if unit.RawSource != "" {
+		call := ModuleCall{
+			Module: relPath,
+			Name:   "terraform",
+			Source: ParseModuleSource(unit.RawSource),
+			File:   filepath.Join(relPath, TerragruntConfigFile),
+		}
+		if _, err := os.Stat(unit.Source); unit.Source != "" && err == nil && IsWithinDirectory(unit.Source, a.root) {
+			call.Local, _ = filepath.Rel(a.root, unit.Source)
+		}
+		a.graph.AddModuleCall(call)
+	}
+	for _, dep := range unit.Dependencies {
+		addEdge(dep, EdgeDependency)
+	}
+	for _, input := range unit.Inputs {
+		addEdge(input, EdgeFileReference)
+		if filepath.Base(input) == TerragruntConfigFile {
+			if relDir, err := filepath.Rel(a.root, filepath.Dir(input)); err == nil {
+				a.includedUnits[relDir] = true
+			}
+		}
+	}
+}
+
+// linkRemoteStates adds an edge from every module reading a remote state to the
+// modules whose backend stores that state.
+func (a *Analyzer) linkRemoteStates() {
+	consumers := make([]string, 0, len(a.remoteStates))
+	for module := range a.remoteStates {
+		consumers = append(consumers, module)
+	}
+	sort.Strings(consumers)
+
+	for _, consumer := range consumers {
+		for _, state := range a.remoteStates[consumer] {
+			for _, producer := range a.backends[state.Key()] {
+				if producer != consumer {
+					a.graph.AddDependencyKind(consumer, producer, EdgeRemoteState)
+				}
+			}
+		}
+	}
+}
+
+// linkRootReferences adds the paths modules reference relative to path.root or path.cwd to
+// the modules calling them. Which module is the root is only known when the affected modules
+// are queried, so the path is resolved against the module itself and every module calling it,
+// transitively, and an edge is added from each of them whose resolved path exists.
+func (a *Analyzer) linkRootReferences() {
+	modules := make([]string, 0, len(a.rootReferences))
+	for module := range a.rootReferences {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+
+	for _, module := range modules {
+		for _, caller := range a.graph.GetAffectedModulesKind(module, EdgeModuleCall) {
+			for _, rel := range a.rootReferences[module] {
+				ref := filepath.Join(a.root, caller, rel)
+				if !IsWithinDirectory(ref, a.root) {
+					continue
+				}
+				if _, err := os.Stat(ref); err != nil {
+					continue
+				}
+				relRef, err := filepath.Rel(a.root, ref)
+				if err != nil || relRef == caller {
+					continue
+				}
+				a.graph.AddDependencyKind(caller, relRef, EdgeFileReference)
+			}
+		}
+	}
+}
+
+// GetAffectedRootModules returns root modules affected by changes in the given paths,
+// using glob patterns to identify root modules.
+func (a *Analyzer) GetAffectedRootModules(changedPaths []string, rootModulePatterns []string) (map[string][]string, error) {
+	return a.GetAffectedRootModulesFunc(changedPaths, func(path string) bool {
+		return isRootModule(path, rootModulePatterns)
+	})
+}
+
+// GetAffectedRootModulesFunc returns root modules affected by changes in the given paths,
+// using a custom matcher function to identify root modules.
+func (a *Analyzer) GetAffectedRootModulesFunc(changedPaths []string, isRoot func(string) bool) (map[string][]string, error) {
+	modules, err := a.AffectedRootModules(changedPaths, isRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	affectedByPath := make(map[string][]string, len(modules))
+	for _, m := range modules {
+		affectedByPath[m.Path] = m.AffectedBy
+	}
+	return affectedByPath, nil
+}
+
+// AffectedRootModules returns root modules affected by changes in the given paths, sorted by path,
+// using a custom matcher function to identify root modules. Each result records why it is affected.
+func (a *Analyzer) AffectedRootModules(changedPaths []string, isRoot func(string) bool) ([]AffectedRootModule, error) {
+	causesByPath := make(map[string][]Cause)
+	deleted := make(map[string]bool)
+
+	var sources []changeSource
+	for _, changePath := range changedPaths {
+		mapped, _ := a.mapChange(changePath)
+		sources = append(sources, mapped...)
+	}
+	sources = append(sources, a.parseErrorSources()...)
+
+	var paths []string
+	for _, source := range sources {
+		if source.reason == ReasonInherited || source.reason == ReasonGlobalTrigger {
+			continue
+		}
+		paths = append(paths, source.path)
+		if source.deleted {
+			deleted[source.path] = true
+		}
+	}
+
+	// Modules reached through module calls and file references alone keep the reason of
+	// the change; otherwise the reason is that of the first other edge kind needed to reach them.
+	// Each pass traverses the graph once for all changed paths.
+	passes := []struct {
+		reason Reason
+		kinds  []EdgeKind
+	}{
+		{"", []EdgeKind{EdgeModuleCall, EdgeFileReference}},
+		{ReasonDependency, []EdgeKind{EdgeModuleCall, EdgeFileReference, EdgeDependency}},
+		{ReasonRemoteState, nil},
+	}
+	passByPath := make(map[string]map[string]int)
+	for i, pass := range passes {
+		for module, reachedBy := range a.graph.AffectedModulesBySource(paths, pass.kinds...) {
+			if !isRoot(module) {
+				continue
+			}
+			if passByPath[module] == nil {
+				passByPath[module] = make(map[string]int)
+			}
+			for _, p := range reachedBy {
+				if _, ok := passByPath[module][p]; !ok {
+					passByPath[module][p] = i
+				}
+			}
+		}
+	}
+
+	// Inherited files affect the root modules beneath their directory only, and global
+	// triggers the root modules they select.
+	candidates := make(map[string]bool, len(passByPath))
+	for module := range passByPath {
+		candidates[module] = true
+	}
+	for _, source := range sources {
+		if source.reason != ReasonInherited && source.reason != ReasonGlobalTrigger {
+			continue
+		}
+		for _, module := range a.modules {
+			if isRoot(module) && a.reachesDirectly(source, module) {
+				candidates[module] = true
+			}
+		}
+	}
+
+	for module := range candidates {
+		for _, source := range sources {
+			if source.reason == ReasonInherited || source.reason == ReasonGlobalTrigger {
+				if a.reachesDirectly(source, module) {
+					causesByPath[module] = append(causesByPath[module], Cause{Path: source.path, Reason: source.reason})
+				}
+				continue
+			}
+			if i, ok := passByPath[module][source.path]; ok {
+				reason := source.reason
+				if i > 0 {
+					reason = passes[i].reason
+				}
+				causesByPath[module] = append(causesByPath[module], Cause{Path: source.path, Reason: reason})
+			}
+		}
+	}
+
+	// Chains are built per changed path, so the graph is traversed once for each of them.
+	modulesByCause := make(map[string][]string)
+	for module, causes := range causesByPath {
+		causesByPath[module] = Unique(causes)
+		for _, c := range causesByPath[module] {
+			modulesByCause[c.Path] = append(modulesByCause[c.Path], module)
+		}
+	}
+	chainsByCause := make(map[string]map[string]Chain, len(modulesByCause))
+	for cause, modules := range modulesByCause {
+		chainsByCause[cause] = a.graph.ChainsFrom(modules, cause)
+	}
+
+	modules := make([]AffectedRootModule, 0, len(causesByPath))
+	for module, causes := range causesByPath {
+		affectedBy := make([]string, 0, len(causes))
+		for _, c := range causes {
+			affectedBy = append(affectedBy, c.Path)
+		}
+		var chains []Chain
+		for _, c := range causes {
+			if chain, ok := chainsByCause[c.Path][module]; ok && len(chain.Steps) > 0 {
+				chains = append(chains, chain)
+			}
+		}
+		modules = append(modules, AffectedRootModule{
+			Path:       module,
+			AffectedBy: Unique(affectedBy),
+			Causes:     causes,
+			Chains:     chains,
+			Deleted:    deleted[module],
+		})
+	}
+
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Path < modules[j].Path
+	})
+
+	return modules, nil
+}
+
+// reachesDirectly reports whether an inherited file or global trigger source affects the module
+// without a dependency chain.
+func (a *Analyzer) reachesDirectly(source changeSource, module string) bool {
+	switch source.reason {
+	case ReasonInherited:
+		return IsWithinDirectory(module, filepath.Dir(source.path))
+	case ReasonGlobalTrigger:
+		return a.globallyTriggers(source.path, module)
+	default:
+		return false
+	}
+}
+
+// parseErrorSources returns the modules that failed to parse as changed when the parse error
+// policy treats them as affected. They may have lost dependencies.
+func (a *Analyzer) parseErrorSources() []changeSource {
+	if a.parseErrorPolicy != ParseErrorTreatAsAffected {
+		return nil
+	}
+	var sources []changeSource
+	for _, module := range a.ParseErrors() {
+		sources = append(sources, changeSource{path: module, reason: ReasonParseError})
+	}
+	return sources
+}
+
+// ChangeStatus classifies how a changed path maps onto the dependency graph.
+type ChangeStatus string
+
+const (
+	// ChangeMapped is a path mapped to a module, a referenced file or an inherited file.
+	ChangeMapped ChangeStatus = "mapped"
+	// ChangeOutsideRoot is a path outside the root directory.
+	ChangeOutsideRoot ChangeStatus = "outside_root"
+	// ChangeNoModule is a path without a parent directory containing Terraform files.
+	ChangeNoModule ChangeStatus = "no_module"
+	// ChangeShadowed is a configuration file that is not loaded in the current mode,
+	// e.g. a .tf file shadowed by a .tofu file.
+	ChangeShadowed ChangeStatus = "shadowed"
+	// ChangeIgnored is a path matched by an ignore rule, left out of the analysis.
+	ChangeIgnored ChangeStatus = "ignored"
+)
+
+// ChangedFile is a changed path together with what it maps to in the dependency graph.
+type ChangedFile struct {
+	Path   string       `json:"path"`
+	Status ChangeStatus `json:"status"`
+	// Sources are the modules, referenced files or inherited files the path maps to.
+	Sources []Cause `json:"sources,omitempty"`
+	// IgnoredBy is the ignore rule matching an ignored path, e.g. ".tarmignore:3: *.md".
+	IgnoredBy string `json:"ignored_by,omitempty"`
+}
+
+// Mapped reports whether the path maps to anything in the dependency graph.
+func (f ChangedFile) Mapped() bool {
+	return f.Status == ChangeMapped
+}
+
+// ChangedFiles returns how each of the changed paths maps onto the dependency graph, in the given order.
+func (a *Analyzer) ChangedFiles(changedPaths []string) []ChangedFile {
+	files := make([]ChangedFile, 0, len(changedPaths))
+	for _, changePath := range changedPaths {
+		sources, status := a.mapChange(changePath)
+		f := ChangedFile{Path: changePath, Status: status}
+		for _, source := range sources {
+			f.Sources = append(f.Sources, Cause{Path: source.path, Reason: source.reason})
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// changeSource is a node of the dependency graph affected by a changed path.
+type changeSource struct {
+	// path is relative to the root directory. For inherited files it is the file itself.
+	path    string
+	reason  Reason
+	deleted bool
+}
+
+// mappedChange is the result of mapChange.
+type mappedChange struct {
+	sources []changeSource
+	status  ChangeStatus
+}
+
+// mapChange returns the nodes of the dependency graph a changed path affects, and how it was mapped.
+func (a *Analyzer) mapChange(changePath string) ([]changeSource, ChangeStatus) {
+	if m, ok := a.mappedChanges[changePath]; ok {
+		return m.sources, m.status
+	}
+	sources, status := a.resolveChange(changePath)
+	// Global triggers affect root modules in addition to whatever the path maps to.
+	if source, ok := a.globalTriggerSource(changePath); ok {
+		sources, status = append(sources, source), ChangeMapped
+	}
+	if a.mappedChanges == nil {
+		a.mappedChanges = make(map[string]mappedChange)
+	}
+	a.mappedChanges[changePath] = mappedChange{sources: sources, status: status}
+	return sources, status
+}
+
+// resolveChange maps a changed path onto the dependency graph.
+func (a *Analyzer) resolveChange(changePath string) ([]changeSource, ChangeStatus) {
+	if !filepath.IsAbs(changePath) {
+		changePath = filepath.Join(a.root, changePath)
+	}
+	if !IsWithinDirectory(changePath, a.root) {
+		return nil, ChangeOutsideRoot
+	}
+	relChangePath, err := filepath.Rel(a.root, changePath)
+	if err != nil {
+		return nil, ChangeOutsideRoot
+	}
+
+	if a.isInheritedFile(relChangePath) {
+		return []changeSource{{path: relChangePath, reason: ReasonInherited}}, ChangeMapped
+	}
+
+	// Files referenced via file(), templatefile() etc. affect the modules that
+	// read them. Module files are still mapped to their own module below.
+	var sources []changeSource
+	for _, ref := range a.graph.FileReferences() {
+		if IsWithinDirectory(relChangePath, ref) {
+			sources = append(sources, changeSource{path: ref, reason: ReasonFileReference})
+		}
+	}
+	if len(sources) > 0 && !isModuleFile(filepath.Base(changePath)) {
+		return sources, ChangeMapped
+	}
+
+	// Configuration files that are not loaded in the current mode cannot affect anything.
+	if isShadowedFile(changePath, a.tofu) {
+		return nil, ChangeShadowed
+	}
+
+	tfDir, moduleDeleted, err := a.findChangedModule(changePath)
+	if err != nil {
+		a.diagnose(Diagnostic{Code: DiagPathError, Message: fmt.Sprintf("failed to find parent with .tf files for %s: %v", changePath, err), File: changePath})
+	}
+	if tfDir == "" {
+		if len(sources) > 0 {
+			return sources, ChangeMapped
+		}
+		return nil, ChangeNoModule
+	}
+
+	relTfDir, err := filepath.Rel(a.root, tfDir)
+	if err != nil {
+		a.diagnose(Diagnostic{Code: DiagPathError, Message: fmt.Sprintf("failed to get relative path for %s: %v", tfDir, err), File: changePath})
+		return sources, ChangeNoModule
+	}
+	sources = append(sources, changeSource{path: relTfDir, reason: ReasonModule, deleted: moduleDeleted})
+	return sources, ChangeMapped
+}
+
+// rewriteSource returns the absolute local directory a remote module source is rewritten to
+// by the first matching rule, or an empty string if no rule applies.
+func (a *Analyzer) rewriteSource(source, version string) string {
+	for _, rule := range a.sourceRewrites {
+		local, ok := rule.Rewrite(source, version)
+		if !ok {
+			continue
+		}
+		resolved := filepath.Join(a.root, filepath.FromSlash(local))
+		if !IsWithinDirectory(resolved, a.root) {
+			return ""
+		}
+		return resolved
+	}
+	return ""
+}
+
+// isInheritedFile reports whether the path, relative to the root directory, matches an inherited file pattern.
+func (a *Analyzer) isInheritedFile(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	for _, pattern := range a.inheritedFilePatterns {
+		name := relPath
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(relPath)
+		}
+		if matched, err := doublestar.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// findChangedModule returns the module directory a changed path belongs to.
+// Paths that no longer exist are mapped to the nearest directory that is a module
+// now or was one in the base tree; deleted reports whether that module no longer exists.
+func (a *Analyzer) findChangedModule(changePath string) (dir string, deleted bool, err error) {
+	if _, err := os.Stat(changePath); err == nil {
+		dir, err := FindParentWithTerraformFiles(changePath, a.root)
+		return dir, false, err
+	}
+
+	for current := filepath.Dir(changePath); IsWithinDirectory(current, a.root); current = filepath.Dir(current) {
+		if info, err := os.Stat(current); err == nil && info.IsDir() {
+			hasTfFiles, err := containsTerraformFiles(current)
+			if err != nil {
+				return "", false, err
+			}
+			if hasTfFiles {
+				return current, false, nil
+			}
+		}
+
+		if relCurrent, err := filepath.Rel(a.root, current); err == nil && a.baseModules[relCurrent] {
+			return current, true, nil
+		}
+
+		if current == a.root {
+			break
+		}
+	}
+
+	return "", false, nil
+}
+
+// TerragruntUnits returns the Terragrunt units found during analysis, sorted.
+// Directories whose terragrunt.hcl is only included by other units are not returned.
+func (a *Analyzer) TerragruntUnits() []string {
+	var units []string
+	for _, unit := range a.units {
+		if !a.includedUnits[unit] {
+			units = append(units, unit)
+		}
+	}
+	sort.Strings(units)
+	return units
+}
+
+// GetDependencyGraph returns the dependency graph.
+func (a *Analyzer) GetDependencyGraph() *DependencyGraph {
+	return a.graph
+}
+
+// Diagnostics returns the diagnostics reported so far, in the order they were reported.
+func (a *Analyzer) Diagnostics() []Diagnostic {
+	return a.diagnostics
+}
+
+// diagnose records a diagnostic and passes it to the diagnostic handler, if any.
+// Diagnostics without a severity are warnings.
+func (a *Analyzer) diagnose(d Diagnostic) {
+	if d.Severity == "" {
+		d.Severity = SeverityWarning
+	}
+	a.diagnostics = append(a.diagnostics, d)
+	if a.diagnosticHandler != nil {
+		a.diagnosticHandler(d)
+	}
+}
+
+func containsTerraformFiles(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() && isModuleFile(entry.Name()) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func isRootModule(modulePath string, patterns []string) bool {
+	for _, pattern := range patterns {
+		matched, err := doublestar.Match(pattern, modulePath)
+		if err != nil {
+			continue
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,1038 @@
+package tarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzer_Analyze(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform")
+
+	analyzer := NewAnalyzer(testRoot)
+	err := analyzer.Analyze(t.Context())
+	if err != nil {
+		t.Fatalf("Analyze() failed: %v", err)
+	}
+
+	graph := analyzer.GetDependencyGraph()
+
+	tests := []struct {
+		name     string
+		module   string
+		wantDeps []string
+	}{
+		{name: "dev/api dependencies", module: "environments/dev/api", wantDeps: []string{"modules/network", "modules/database"}},
+		{name: "dev/web dependencies", module: "environments/dev/web", wantDeps: []string{"modules/network", "modules/auth"}},
+		{name: "stg/api dependencies", module: "environments/stg/api", wantDeps: []string{"modules/network", "modules/database", "modules/auth"}},
+		{name: "auth module dependencies", module: "modules/auth", wantDeps: []string{"modules/common"}},
+		{name: "database module dependencies", module: "modules/database", wantDeps: []string{"modules/common"}},
+		{name: "common module has no dependencies", module: "modules/common", wantDeps: []string{}},
+		{name: "prod/app only tracks local modules", module: "environments/prod/app", wantDeps: []string{"modules/network"}},
+		{name: "empty module has no dependencies", module: "modules/empty", wantDeps: []string{}},
+		{name: "standalone module has no local dependencies", module: "environments/standalone/simple", wantDeps: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := graph.Dependencies(tt.module)
+			if len(deps) != len(tt.wantDeps) {
+				t.Errorf("Module %s: got %d deps %v, want %d %v", tt.module, len(deps), deps, len(tt.wantDeps), tt.wantDeps)
+				return
+			}
+			depSet := make(map[string]bool)
+			for _, dep := range deps {
+				depSet[dep] = true
+			}
+			for _, want := range tt.wantDeps {
+				if !depSet[want] {
+					t.Errorf("Module %s: missing dependency %s", tt.module, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_GetAffectedRootModules(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform")
+
+	analyzer := NewAnalyzer(testRoot)
+	if err := analyzer.Analyze(t.Context()); err != nil {
+		t.Fatalf("Analyze() failed: %v", err)
+	}
+
+	rootPatterns := []string{"environments/*/*"}
+
+	tests := []struct {
+		name         string
+		changedPaths []string
+		wantModules  []string
+	}{
+		{name: "common module change", changedPaths: []string{"modules/common/main.tf"}, wantModules: []string{"environments/dev/web", "environments/stg/api", "environments/stg/web", "environments/dev/api"}},
+		{name: "network module change", changedPaths: []string{"modules/network/main.tf"}, wantModules: []string{"environments/dev/api", "environments/dev/web", "environments/stg/api", "environments/stg/web", "environments/prod/app"}},
+		{name: "auth module change", changedPaths: []string{"modules/auth/main.tf"}, wantModules: []string{"environments/dev/web", "environments/stg/api", "environments/stg/web"}},
+		{name: "database module change", changedPaths: []string{"modules/database/main.tf"}, wantModules: []string{"environments/dev/api", "environments/stg/api"}},
+		{name: "direct root module change", changedPaths: []string{"environments/dev/api/main.tf"}, wantModules: []string{"environments/dev/api"}},
+		{name: "empty module change", changedPaths: []string{"modules/empty/main.tf"}, wantModules: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			affected, err := analyzer.GetAffectedRootModules(tt.changedPaths, rootPatterns)
+			if err != nil {
+				t.Fatalf("GetAffectedRootModules() failed: %v", err)
+			}
+
+			if len(affected) != len(tt.wantModules) {
+				var got []string
+				for m := range affected {
+					got = append(got, m)
+				}
+				t.Errorf("got %v, want %v", got, tt.wantModules)
+				return
+			}
+
+			for _, want := range tt.wantModules {
+				if _, ok := affected[want]; !ok {
+					t.Errorf("missing affected module: %s", want)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_ErrorHandling(t *testing.T) {
+	tests := []struct {
+		name     string
+		testRoot string
+	}{
+		{name: "circular dependency", testRoot: filepath.Join("..", "..", "testdata", "terraform-errors", "circular")},
+		{name: "invalid syntax", testRoot: filepath.Join("..", "..", "testdata", "terraform-errors", "invalid-syntax")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewAnalyzer(tt.testRoot)
+			err := analyzer.Analyze(t.Context())
+			if err != nil {
+				t.Logf("Got error (may be expected): %v", err)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_NonTfFileHandling(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform")
+
+	analyzer := NewAnalyzer(testRoot)
+	if err := analyzer.Analyze(t.Context()); err != nil {
+		t.Fatalf("Analyze() failed: %v", err)
+	}
+
+	rootPatterns := []string{"environments/*/*"}
+
+	tests := []struct {
+		name         string
+		changedPaths []string
+		wantModules  []string
+	}{
+		{name: "yaml in same dir as .tf", changedPaths: []string{"environments/dev/api/config.yaml"}, wantModules: []string{"environments/dev/api"}},
+		{name: "nested json escalates to parent", changedPaths: []string{"modules/database/configs/nested/schema.json"}, wantModules: []string{"environments/dev/api", "environments/stg/api"}},
+		{name: "mix of .tf and non-.tf", changedPaths: []string{"environments/dev/api/main.tf", "environments/dev/api/config.yaml"}, wantModules: []string{"environments/dev/api"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			affected, err := analyzer.GetAffectedRootModules(tt.changedPaths, rootPatterns)
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			if len(affected) != len(tt.wantModules) {
+				var got []string
+				for m := range affected {
+					got = append(got, m)
+				}
+				t.Errorf("got %v, want %v", got, tt.wantModules)
+				return
+			}
+
+			for _, want := range tt.wantModules {
+				if _, ok := affected[want]; !ok {
+					t.Errorf("missing: %s", want)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_ComplexDirectoryStructure(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform-complex")
+
+	analyzer := NewAnalyzer(testRoot)
+	if err := analyzer.Analyze(t.Context()); err != nil {
+		t.Fatalf("Analyze() failed: %v", err)
+	}
+
+	graph := analyzer.GetDependencyGraph()
+
+	tests := []struct {
+		name     string
+		module   string
+		wantDeps []string
+	}{
+		{name: "root depends on deep nested", module: ".", wantDeps: []string{"stacks/shared/vpc"}},
+		{name: "cross-directory dependencies", module: "stacks/shared/vpc", wantDeps: []string{"shared-components/logging"}},
+		{name: "reusable modules in non-standard locations", module: "shared-components/logging", wantDeps: []string{"utils/common"}},
+		{name: "app references shared components", module: "apps/web-service", wantDeps: []string{"shared-components/logging", "utils/common"}},
+		{name: "team-based structure", module: "team-a/project-x", wantDeps: []string{"shared-components/logging", "utils/common"}},
+		{name: "utility modules have no dependencies", module: "utils/common", wantDeps: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := graph.Dependencies(tt.module)
+			if len(deps) != len(tt.wantDeps) {
+				t.Errorf("Module %s: got %v, want %v", tt.module, deps, tt.wantDeps)
+				return
+			}
+			depSet := make(map[string]bool)
+			for _, dep := range deps {
+				depSet[dep] = true
+			}
+			for _, want := range tt.wantDeps {
+				if !depSet[want] {
+					t.Errorf("Module %s: missing dependency %s", tt.module, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_ComplexRootModuleDetection(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform-complex")
+
+	analyzer := NewAnalyzer(testRoot)
+	if err := analyzer.Analyze(t.Context()); err != nil {
+		t.Fatalf("Analyze() failed: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		changedPaths []string
+		rootPatterns []string
+		wantModules  []string
+	}{
+		{name: "utility module affects consumers", changedPaths: []string{"utils/common/main.tf"}, rootPatterns: []string{"apps/*", "team-*/*", "stacks/*/*"}, wantModules: []string{"apps/web-service", "team-a/project-x", "stacks/shared/vpc"}},
+		{name: "shared component affects consumers", changedPaths: []string{"shared-components/logging/main.tf"}, rootPatterns: []string{"*", "apps/*", "team-*/*", "stacks/*/*"}, wantModules: []string{".", "apps/web-service", "team-a/project-x", "stacks/shared/vpc"}},
+		{name: "root module at repository root", changedPaths: []string{"main.tf"}, rootPatterns: []string{"*"}, wantModules: []string{"."}},
+		{name: "doublestar matches all", changedPaths: []string{"utils/common/main.tf"}, rootPatterns: []string{"**/*"}, wantModules: []string{"utils/common", "shared-components/logging", "apps/web-service", "team-a/project-x", "stacks/shared/vpc", "."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			affected, err := analyzer.GetAffectedRootModules(tt.changedPaths, tt.rootPatterns)
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			if len(affected) != len(tt.wantModules) {
+				var got []string
+				for m := range affected {
+					got = append(got, m)
+				}
+				t.Errorf("got %v, want %v", got, tt.wantModules)
+				return
+			}
+
+			for _, want := range tt.wantModules {
+				if _, ok := affected[want]; !ok {
+					t.Errorf("missing: %s", want)
+				}
+			}
+		})
+	}
+}
+
+func TestIsRootModule(t *testing.T) {
+	tests := []struct {
+		name        string
+		modulePath  string
+		patterns    []string
+		wantMatched bool
+	}{
+		{name: "environments pattern matches", modulePath: "environments/dev/api", patterns: []string{"environments/*/*"}, wantMatched: true},
+		{name: "modules should not match environments", modulePath: "modules/auth", patterns: []string{"environments/*/*"}, wantMatched: false},
+		{name: "multiple patterns", modulePath: "environments/dev/api", patterns: []string{"modules/*", "environments/*/*"}, wantMatched: true},
+		{name: "doublestar matches multi-level", modulePath: "stacks/shared/vpc", patterns: []string{"stacks/**"}, wantMatched: true},
+		{name: "doublestar in middle", modulePath: "infra/eu-west-1/prod/vpc", patterns: []string{"infra/**/vpc"}, wantMatched: true},
+		{name: "doublestar no match on prefix", modulePath: "other/shared/vpc", patterns: []string{"stacks/**"}, wantMatched: false},
+		{name: "single star no multi-level", modulePath: "stacks/shared/vpc", patterns: []string{"stacks/*"}, wantMatched: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched := isRootModule(tt.modulePath, tt.patterns)
+			if matched != tt.wantMatched {
+				t.Errorf("isRootModule(%s, %v) = %v, want %v", tt.modulePath, tt.patterns, matched, tt.wantMatched)
+			}
+		})
+	}
+}
+
+// Silence the unused import warning for strings
+var _ = strings.Contains
+
+func TestAnalyzer_FileReferences(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform-assets")
+
+	analyzer := NewAnalyzer(testRoot)
+	if err := analyzer.Analyze(t.Context()); err != nil {
+		t.Fatalf("Analyze() failed: %v", err)
+	}
+
+	rootPatterns := []string{"environments/*"}
+
+	tests := []struct {
+		name           string
+		changedPaths   []string
+		wantModules    []string
+		wantAffectedBy []string
+	}{
+		{name: "lambda handler source", changedPaths: []string{"modules/lambda/src/handler/index.py"}, wantModules: []string{"environments/dev", "environments/prod"}, wantAffectedBy: []string{"modules/lambda/src/handler"}},
+		{name: "policy outside any module", changedPaths: []string{"policies/lambda.json"}, wantModules: []string{"environments/dev", "environments/prod"}, wantAffectedBy: []string{"policies/lambda.json"}},
+		{name: "template read via path.root", changedPaths: []string{"environments/prod/templates/user_data.tpl"}, wantModules: []string{"environments/prod"}, wantAffectedBy: []string{"environments/prod/templates/user_data.tpl"}},
+		{name: "file read via path.root in a child module", changedPaths: []string{"environments/dev/config/lambda.json"}, wantModules: []string{"environments/dev"}, wantAffectedBy: []string{"environments/dev/config/lambda.json"}},
+		{name: "file read via a relative path in a child module", changedPaths: []string{"environments/prod/config/tags.json"}, wantModules: []string{"environments/prod"}, wantAffectedBy: []string{"environments/prod/config/tags.json"}},
+		{name: "fileset directory", changedPaths: []string{"scripts/deploy.sh"}, wantModules: []string{"environments/prod"}, wantAffectedBy: []string{"scripts"}},
+		{name: "terraform file in module", changedPaths: []string{"modules/lambda/main.tf"}, wantModules: []string{"environments/dev", "environments/prod"}, wantAffectedBy: []string{"modules/lambda"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			affected, err := analyzer.GetAffectedRootModules(tt.changedPaths, rootPatterns)
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			if len(affected) != len(tt.wantModules) {
+				var got []string
+				for m := range affected {
+					got = append(got, m)
+				}
+				t.Errorf("got %v, want %v", got, tt.wantModules)
+				return
+			}
+
+			for _, want := range tt.wantModules {
+				affectedBy, ok := affected[want]
+				if !ok {
+					t.Errorf("missing: %s", want)
+					continue
+				}
+				if len(affectedBy) != len(tt.wantAffectedBy) {
+					t.Errorf("%s: affected by %v, want %v", want, affectedBy, tt.wantAffectedBy)
+					continue
+				}
+				for i := range affectedBy {
+					if affectedBy[i] != tt.wantAffectedBy[i] {
+						t.Errorf("%s: affected by %v, want %v", want, affectedBy, tt.wantAffectedBy)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_RemoteState(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform-remote-state")
+
+	analyzer := NewAnalyzer(testRoot)
+	if err := analyzer.Analyze(t.Context()); err != nil {
+		t.Fatalf("Analyze() failed: %v", err)
+	}
+
+	graph := analyzer.GetDependencyGraph()
+
+	edges := []struct {
+		from string
+		to   string
+		kind EdgeKind
+	}{
+		{"stacks/shared/vpc", "modules/network", EdgeModuleCall},
+		{"apps/web", "stacks/shared/vpc", EdgeRemoteState},
+		{"apps/api", "apps/web", EdgeRemoteState},
+		{"apps/api", "stacks/shared/dns", EdgeRemoteState},
+		{"apps/report", "apps/batch", EdgeRemoteState},
+	}
+	for _, e := range edges {
+		if got := graph.Kind(e.from, e.to); got != e.kind {
+			t.Errorf("Kind(%s, %s) = %q, want %q", e.from, e.to, got, e.kind)
+		}
+	}
+	if deps := graph.Dependencies("apps/batch"); len(deps) != 0 {
+		t.Errorf("apps/batch: got deps %v, want none", deps)
+	}
+
+	rootPatterns := []string{"stacks/*/*", "apps/*"}
+
+	tests := []struct {
+		name         string
+		changedPaths []string
+		wantReasons  map[string]Reason
+	}{
+		{
+			name:         "module change propagates through remote state",
+			changedPaths: []string{"modules/network/main.tf"},
+			wantReasons:  map[string]Reason{"stacks/shared/vpc": ReasonModule, "apps/web": ReasonRemoteState, "apps/api": ReasonRemoteState},
+		},
+		{
+			name:         "root change affects direct consumers",
+			changedPaths: []string{"apps/web/main.tf"},
+			wantReasons:  map[string]Reason{"apps/web": ReasonModule, "apps/api": ReasonRemoteState},
+		},
+		{
+			name:         "cloud workspace consumer",
+			changedPaths: []string{"apps/batch/main.tf"},
+			wantReasons:  map[string]Reason{"apps/batch": ReasonModule, "apps/report": ReasonRemoteState},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modules, err := analyzer.AffectedRootModules(tt.changedPaths, func(path string) bool {
+				return isRootModule(path, rootPatterns)
+			})
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			if len(modules) != len(tt.wantReasons) {
+				t.Fatalf("got %v, want %v", modules, tt.wantReasons)
+			}
+			for _, m := range modules {
+				want, ok := tt.wantReasons[m.Path]
+				if !ok {
+					t.Errorf("unexpected module %s", m.Path)
+					continue
+				}
+				if len(m.Causes) != 1 || m.Causes[0].Reason != want {
+					t.Errorf("%s: causes %v, want reason %q", m.Path, m.Causes, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_Terragrunt(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terragrunt")
+
+	analyzer := NewAnalyzer(testRoot)
+	if err := analyzer.Analyze(t.Context()); err != nil {
+		t.Fatalf("Analyze() failed: %v", err)
+	}
+
+	units := analyzer.TerragruntUnits()
+	wantUnits := []string{"live/dev/vpc", "live/prod/app", "live/prod/vpc", "live/prod/worker"}
+	if strings.Join(units, ",") != strings.Join(wantUnits, ",") {
+		t.Errorf("TerragruntUnits() = %v, want %v", units, wantUnits)
+	}
+
+	isUnit := func(path string) bool {
+		for _, unit := range units {
+			if unit == path {
+				return true
+			}
+		}
+		return false
+	}
+
+	tests := []struct {
+		name         string
+		changedPaths []string
+		wantReasons  map[string]Reason
+	}{
+		{
+			name:         "parent root.hcl affects every unit",
+			changedPaths: []string{"root.hcl"},
+			wantReasons:  map[string]Reason{"live/dev/vpc": ReasonFileReference, "live/prod/app": ReasonFileReference, "live/prod/vpc": ReasonFileReference, "live/prod/worker": ReasonFileReference},
+		},
+		{
+			name:         "module source change follows dependency blocks",
+			changedPaths: []string{"modules/vpc/main.tf"},
+			wantReasons:  map[string]Reason{"live/dev/vpc": ReasonModule, "live/prod/vpc": ReasonModule, "live/prod/app": ReasonDependency, "live/prod/worker": ReasonDependency},
+		},
+		{
+			name:         "read_terragrunt_config input",
+			changedPaths: []string{"live/prod/env.hcl"},
+			wantReasons:  map[string]Reason{"live/prod/vpc": ReasonFileReference, "live/prod/app": ReasonDependency, "live/prod/worker": ReasonDependency},
+		},
+		{
+			name:         "unit configuration change",
+			changedPaths: []string{"live/prod/app/terragrunt.hcl"},
+			wantReasons:  map[string]Reason{"live/prod/app": ReasonModule, "live/prod/worker": ReasonDependency},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modules, err := analyzer.AffectedRootModules(tt.changedPaths, isUnit)
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			if len(modules) != len(tt.wantReasons) {
+				t.Fatalf("got %v, want %v", modules, tt.wantReasons)
+			}
+			for _, m := range modules {
+				want, ok := tt.wantReasons[m.Path]
+				if !ok {
+					t.Errorf("unexpected module %s", m.Path)
+					continue
+				}
+				if len(m.Causes) != 1 || m.Causes[0].Reason != want {
+					t.Errorf("%s: causes %v, want reason %q", m.Path, m.Causes, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_DeletedPaths(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform")
+
+	baseTree := []string{
+		"environments/dev/api/main.tf",
+		"environments/dev/legacy/main.tf",
+		"environments/dev/legacy/variables.tf",
+		"modules/database/main.tf",
+		"modules/database/outputs.tf",
+		"modules/queue/main.tf",
+	}
+
+	rootPatterns := []string{"environments/*/*"}
+
+	tests := []struct {
+		name         string
+		baseTree     []string
+		changedPaths []string
+		wantModules  []string
+		wantDeleted  []string
+	}{
+		{name: "deleted file in existing module", baseTree: baseTree, changedPaths: []string{"modules/database/outputs.tf"}, wantModules: []string{"environments/dev/api", "environments/stg/api"}},
+		{name: "deleted root module", baseTree: baseTree, changedPaths: []string{"environments/dev/legacy/main.tf", "environments/dev/legacy/variables.tf"}, wantModules: []string{"environments/dev/legacy"}, wantDeleted: []string{"environments/dev/legacy"}},
+		{name: "deleted non-root module without consumers", baseTree: baseTree, changedPaths: []string{"modules/queue/main.tf"}, wantModules: []string{}},
+		{name: "deleted root module without base tree", changedPaths: []string{"environments/dev/legacy/main.tf"}, wantModules: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewAnalyzer(testRoot, WithBaseTree(tt.baseTree))
+			if err := analyzer.Analyze(t.Context()); err != nil {
+				t.Fatalf("Analyze() failed: %v", err)
+			}
+
+			modules, err := analyzer.AffectedRootModules(tt.changedPaths, func(path string) bool {
+				return isRootModule(path, rootPatterns)
+			})
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			var gotModules, gotDeleted []string
+			for _, m := range modules {
+				gotModules = append(gotModules, m.Path)
+				if m.Deleted {
+					gotDeleted = append(gotDeleted, m.Path)
+				}
+			}
+			if strings.Join(gotModules, ",") != strings.Join(tt.wantModules, ",") {
+				t.Errorf("got %v, want %v", gotModules, tt.wantModules)
+			}
+			if strings.Join(gotDeleted, ",") != strings.Join(tt.wantDeleted, ",") {
+				t.Errorf("got deleted %v, want %v", gotDeleted, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_OpenTofu(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "opentofu")
+
+	tests := []struct {
+		name         string
+		opts         []AnalyzerOption
+		changedPaths []string
+		wantModules  []string
+	}{
+		{name: "json root module", changedPaths: []string{"modules/network/main.tf"}, wantModules: []string{"environments/json", "environments/mixed"}},
+		{name: "json module", changedPaths: []string{"modules/storage/main.tf.json"}, wantModules: []string{"environments/app"}},
+		{name: "tofu files are not loaded by terraform", changedPaths: []string{"modules/encryption/main.tofu"}, wantModules: []string{}},
+		{name: "tofu files are loaded in opentofu mode", opts: []AnalyzerOption{WithOpenTofu()}, changedPaths: []string{"modules/encryption/main.tofu"}, wantModules: []string{"environments/app", "environments/mixed"}},
+		{name: "shadowed module call is ignored in opentofu mode", opts: []AnalyzerOption{WithOpenTofu()}, changedPaths: []string{"modules/storage/main.tf.json"}, wantModules: []string{}},
+		{name: "change to shadowed file is ignored", opts: []AnalyzerOption{WithOpenTofu()}, changedPaths: []string{"environments/app/main.tf"}, wantModules: []string{}},
+		{name: "change to tf file in terraform mode", changedPaths: []string{"environments/app/main.tf"}, wantModules: []string{"environments/app"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewAnalyzer(testRoot, tt.opts...)
+			if err := analyzer.Analyze(t.Context()); err != nil {
+				t.Fatalf("Analyze() failed: %v", err)
+			}
+
+			affected, err := analyzer.GetAffectedRootModules(tt.changedPaths, []string{"environments/*"})
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			if len(affected) != len(tt.wantModules) {
+				t.Errorf("got %v, want %v", affected, tt.wantModules)
+				return
+			}
+			for _, want := range tt.wantModules {
+				if _, ok := affected[want]; !ok {
+					t.Errorf("missing: %s", want)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_InheritedFiles(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform-inherited")
+	opts := []AnalyzerOption{WithInheritedFiles([]string{"backend.hcl", "*.tfvars", ".terraform-version"})}
+
+	tests := []struct {
+		name         string
+		opts         []AnalyzerOption
+		changedPaths []string
+		wantModules  []string
+	}{
+		{name: "backend config affects roots beneath", opts: opts, changedPaths: []string{"environments/prod/backend.hcl"}, wantModules: []string{"environments/prod/app", "environments/prod/vpc"}},
+		{name: "shared tfvars affects roots beneath", opts: opts, changedPaths: []string{"environments/dev/common.tfvars"}, wantModules: []string{"environments/dev/app", "environments/dev/vpc"}},
+		{name: "repository level file affects every root", opts: opts, changedPaths: []string{".terraform-version"}, wantModules: []string{"environments/dev/app", "environments/dev/vpc", "environments/prod/app", "environments/prod/vpc"}},
+		{name: "deleted inherited file", opts: opts, changedPaths: []string{"environments/prod/vpc/terraform.tfvars"}, wantModules: []string{"environments/prod/vpc"}},
+		{name: "path pattern", opts: []AnalyzerOption{WithInheritedFiles([]string{"environments/prod/*.hcl"})}, changedPaths: []string{"environments/prod/backend.hcl", "environments/dev/backend.hcl"}, wantModules: []string{"environments/prod/app", "environments/prod/vpc"}},
+		{name: "not inherited without patterns", changedPaths: []string{"environments/prod/backend.hcl"}, wantModules: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewAnalyzer(testRoot, tt.opts...)
+			if err := analyzer.Analyze(t.Context()); err != nil {
+				t.Fatalf("Analyze() failed: %v", err)
+			}
+
+			modules, err := analyzer.AffectedRootModules(tt.changedPaths, func(path string) bool {
+				return isRootModule(path, []string{"environments/*/*"})
+			})
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			if len(modules) != len(tt.wantModules) {
+				t.Fatalf("got %v, want %v", modules, tt.wantModules)
+			}
+			for i, m := range modules {
+				if m.Path != tt.wantModules[i] {
+					t.Errorf("module[%d] = %s, want %s", i, m.Path, tt.wantModules[i])
+				}
+				if m.ReasonFor(m.AffectedBy[0]) != ReasonInherited {
+					t.Errorf("%s: reason = %s, want %s", m.Path, m.ReasonFor(m.AffectedBy[0]), ReasonInherited)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_SourceRewrites(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform-rewrite")
+	rules := []SourceRewrite{
+		{Pattern: "git::https://github.com/our-org/infra.git", Path: "."},
+		{Pattern: "app.terraform.io/our-org/{name}/aws", Path: "modules/{name}"},
+	}
+	floatingRules := []SourceRewrite{
+		{Pattern: "git::https://github.com/our-org/infra.git", Path: ".", FloatingOnly: true},
+		{Pattern: "app.terraform.io/our-org/{name}/aws", Path: "modules/{name}", FloatingOnly: true},
+	}
+
+	tests := []struct {
+		name         string
+		opts         []AnalyzerOption
+		changedPaths []string
+		wantModules  []string
+	}{
+		{name: "remote sources are ignored without rules", changedPaths: []string{"modules/network/main.tf", "modules/storage/main.tf"}, wantModules: []string{}},
+		{name: "git source", opts: []AnalyzerOption{WithSourceRewrites(rules)}, changedPaths: []string{"modules/network/main.tf"}, wantModules: []string{"environments/branch", "environments/tagged"}},
+		{name: "registry source", opts: []AnalyzerOption{WithSourceRewrites(rules)}, changedPaths: []string{"modules/storage/main.tf"}, wantModules: []string{"environments/pinned", "environments/registry"}},
+		{name: "floating git ref only", opts: []AnalyzerOption{WithSourceRewrites(floatingRules)}, changedPaths: []string{"modules/network/main.tf"}, wantModules: []string{"environments/branch"}},
+		{name: "floating registry version only", opts: []AnalyzerOption{WithSourceRewrites(floatingRules)}, changedPaths: []string{"modules/storage/main.tf"}, wantModules: []string{"environments/registry"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewAnalyzer(testRoot, tt.opts...)
+			if err := analyzer.Analyze(t.Context()); err != nil {
+				t.Fatalf("Analyze() failed: %v", err)
+			}
+
+			affected, err := analyzer.GetAffectedRootModules(tt.changedPaths, []string{"environments/*"})
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			if len(affected) != len(tt.wantModules) {
+				t.Errorf("got %v, want %v", affected, tt.wantModules)
+				return
+			}
+			for _, want := range tt.wantModules {
+				if _, ok := affected[want]; !ok {
+					t.Errorf("missing: %s", want)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_ModuleCalls(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform-rewrite")
+
+	analyzer := NewAnalyzer(testRoot, WithSourceRewrites([]SourceRewrite{
+		{Pattern: "git::https://github.com/our-org/infra.git", Path: "."},
+	}))
+	if err := analyzer.Analyze(t.Context()); err != nil {
+		t.Fatalf("Analyze() failed: %v", err)
+	}
+
+	type call struct {
+		module, name string
+		kind         SourceKind
+		local        string
+		line         int
+	}
+	want := []call{
+		{"environments/branch", "network", SourceGit, "modules/network", 1},
+		{"environments/external", "vpc", SourceRegistry, "", 1},
+		{"environments/external", "network", SourceGit, "", 6},
+		{"environments/pinned", "storage", SourceRegistry, "", 1},
+		{"environments/registry", "storage", SourceRegistry, "", 1},
+		{"environments/tagged", "network", SourceGit, "modules/network", 1},
+	}
+
+	calls := analyzer.GetDependencyGraph().ModuleCalls()
+	if len(calls) != len(want) {
+		t.Fatalf("got %d calls %+v, want %d", len(calls), calls, len(want))
+	}
+	for i, c := range calls {
+		got := call{c.Module, c.Name, c.Source.Kind, c.Local, c.Line}
+		if got != want[i] {
+			t.Errorf("call[%d] = %+v, want %+v", i, got, want[i])
+		}
+		if c.File != filepath.Join(c.Module, "main.tf") {
+			t.Errorf("call[%d] file = %s, want %s", i, c.File, filepath.Join(c.Module, "main.tf"))
+		}
+	}
+}
+
+func TestAnalyzer_ParseErrors(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform-parse-errors")
+
+	tests := []struct {
+		name         string
+		opts         []AnalyzerOption
+		changedPaths []string
+		wantModules  []string
+		wantReasons  map[string]Reason
+	}{
+		{
+			name:         "fallback scanner keeps module calls",
+			changedPaths: []string{"modules/network/main.tf"},
+			wantModules:  []string{"environments/broken", "environments/ok"},
+		},
+		{
+			name:         "module call after a syntax error",
+			changedPaths: []string{"modules/storage/main.tf"},
+			wantModules:  []string{"environments/broken"},
+		},
+		{
+			name:         "skip does not add modules that failed to parse",
+			opts:         []AnalyzerOption{WithParseErrorPolicy(ParseErrorSkip)},
+			changedPaths: []string{"environments/ok/main.tf"},
+			wantModules:  []string{"environments/ok"},
+		},
+		{
+			name:         "treat-as-affected adds modules that failed to parse",
+			opts:         []AnalyzerOption{WithParseErrorPolicy(ParseErrorTreatAsAffected)},
+			changedPaths: []string{"environments/ok/main.tf"},
+			wantModules:  []string{"environments/broken", "environments/ok"},
+			wantReasons:  map[string]Reason{"environments/broken": ReasonParseError, "environments/ok": ReasonModule},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewAnalyzer(testRoot, tt.opts...)
+			if err := analyzer.Analyze(t.Context()); err != nil {
+				t.Fatalf("Analyze() failed: %v", err)
+			}
+			if got := analyzer.ParseErrors(); len(got) != 1 || got[0] != "environments/broken" {
+				t.Errorf("ParseErrors() = %v, want [environments/broken]", got)
+			}
+
+			modules, err := analyzer.AffectedRootModules(tt.changedPaths, func(path string) bool {
+				return isRootModule(path, []string{"environments/*"})
+			})
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+
+			if len(modules) != len(tt.wantModules) {
+				t.Fatalf("got %v, want %v", modules, tt.wantModules)
+			}
+			for i, m := range modules {
+				if m.Path != tt.wantModules[i] {
+					t.Errorf("module[%d] = %s, want %s", i, m.Path, tt.wantModules[i])
+				}
+				if want, ok := tt.wantReasons[m.Path]; ok && m.Causes[0].Reason != want {
+					t.Errorf("%s: reason = %s, want %s", m.Path, m.Causes[0].Reason, want)
+				}
+			}
+		})
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		analyzer := NewAnalyzer(testRoot, WithParseErrorPolicy(ParseErrorFail))
+		err := analyzer.Analyze(t.Context())
+		if err == nil || !strings.Contains(err.Error(), "environments/broken") {
+			t.Errorf("Analyze() error = %v, want parse failure of environments/broken", err)
+		}
+	})
+}
+
+// analysisSnapshot renders everything analysis produces, to compare analyses of the same tree.
+func analysisSnapshot(a *Analyzer) string {
+	return fmt.Sprint(a.GetDependencyGraph(), a.GetDependencyGraph().ModuleCalls(), a.modules, a.diagnostics, a.parseErrors, a.units, a.backends, a.remoteStates)
+}
+
+func TestAnalyzer_Concurrency(t *testing.T) {
+	roots := []string{"terraform", "terraform-complex", "terraform-parse-errors", "terraform-remote-state", "terragrunt", "opentofu"}
+
+	for _, root := range roots {
+		t.Run(root, func(t *testing.T) {
+			testRoot := filepath.Join("..", "..", "testdata", root)
+
+			serial := NewAnalyzer(testRoot, WithConcurrency(1))
+			if err := serial.Analyze(t.Context()); err != nil {
+				t.Fatalf("Analyze() failed: %v", err)
+			}
+			want := analysisSnapshot(serial)
+
+			for range 5 {
+				parallel := NewAnalyzer(testRoot, WithConcurrency(8))
+				if err := parallel.Analyze(t.Context()); err != nil {
+					t.Fatalf("Analyze() failed: %v", err)
+				}
+				if got := analysisSnapshot(parallel); got != want {
+					t.Fatalf("concurrent analysis differs:\ngot  %s\nwant %s", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	analyzer := NewAnalyzer(filepath.Join("..", "..", "testdata", "terraform"))
+	if err := analyzer.Analyze(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Analyze() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestAnalyzer_Diagnostics(t *testing.T) {
+	tests := []struct {
+		root string
+		want []Diagnostic
+	}{
+		{
+			root: "terraform-errors",
+			want: []Diagnostic{
+				{Code: DiagParseError, File: "invalid-syntax/main.tf", Line: 11, Module: "invalid-syntax"},
+				{Code: DiagModuleSourceNotFound, File: "invalid-syntax/main.tf", Line: 2, Module: "invalid-syntax"},
+			},
+		},
+		{
+			root: "terraform-parse-errors",
+			want: []Diagnostic{
+				{Code: DiagParseError, File: "environments/broken/main.tf", Line: 6, Module: "environments/broken"},
+			},
+		},
+		{
+			root: "terraform",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.root, func(t *testing.T) {
+			var handled []Diagnostic
+			analyzer := NewAnalyzer(filepath.Join("..", "..", "testdata", tt.root), WithDiagnosticHandler(func(d Diagnostic) {
+				handled = append(handled, d)
+			}))
+			if err := analyzer.Analyze(t.Context()); err != nil {
+				t.Fatalf("Analyze() failed: %v", err)
+			}
+
+			got := analyzer.Diagnostics()
+			if len(got) != len(tt.want) {
+				t.Fatalf("Diagnostics() = %v, want %d diagnostics", got, len(tt.want))
+			}
+			for i, d := range got {
+				want := tt.want[i]
+				if d.Code != want.Code || d.File != want.File || d.Line != want.Line || d.Module != want.Module {
+					t.Errorf("diagnostic[%d] = %+v, want %+v", i, d, want)
+				}
+				if d.Severity != SeverityWarning || d.Message == "" {
+					t.Errorf("diagnostic[%d] = %+v, want a warning with a message", i, d)
+				}
+			}
+			if len(handled) != len(got) {
+				t.Errorf("handled %d diagnostics, want %d", len(handled), len(got))
+			}
+		})
+	}
+}
+
+func TestAnalyzer_Chains(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform")
+
+	analyzer := NewAnalyzer(testRoot)
+	if err := analyzer.Analyze(t.Context()); err != nil {
+		t.Fatalf("Analyze() failed: %v", err)
+	}
+
+	modules, err := analyzer.AffectedRootModules([]string{"modules/common/main.tf", "environments/dev/api/main.tf"}, func(path string) bool {
+		return path == "environments/dev/api"
+	})
+	if err != nil {
+		t.Fatalf("failed: %v", err)
+	}
+	if len(modules) != 1 {
+		t.Fatalf("got %v, want environments/dev/api only", modules)
+	}
+
+	m := modules[0]
+	if len(m.Chains) != 1 {
+		t.Fatalf("got %d chains %+v, want 1 (direct changes have no chain)", len(m.Chains), m.Chains)
+	}
+	want := "environments/dev/api -> module.database (environments/dev/api/main.tf:6) -> modules/database -> module.common (modules/database/main.tf:5) -> modules/common"
+	if got := m.Chains[0].String(); got != want {
+		t.Errorf("chain = %q, want %q", got, want)
+	}
+	if got := m.ChainsFor("modules/common"); len(got) != 1 {
+		t.Errorf("ChainsFor(modules/common) = %v, want 1 chain", got)
+	}
+}
+
+func TestAnalyzer_DuplicateBackend(t *testing.T) {
+	root := t.TempDir()
+	backend := "terraform {\n  backend \"s3\" {\n    bucket = \"tfstate\"\n    key    = \"shared.tfstate\"\n  }\n}\n"
+	files := map[string]string{
+		"stacks/a/main.tf": backend,
+		"stacks/b/main.tf": backend,
+		"apps/web/main.tf": "data \"terraform_remote_state\" \"shared\" {\n  backend = \"s3\"\n  config = {\n    bucket = \"tfstate\"\n    key    = \"shared.tfstate\"\n  }\n}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	analyzer := NewAnalyzer(root)
+	if err := analyzer.Analyze(t.Context()); err != nil {
+		t.Fatalf("Analyze() failed: %v", err)
+	}
+
+	if got, want := analyzer.GetDependencyGraph().Dependencies("apps/web"), []string{"stacks/a", "stacks/b"}; !slices.Equal(got, want) {
+		t.Errorf("Dependencies(apps/web) = %v, want %v", got, want)
+	}
+	var codes []DiagnosticCode
+	for _, d := range analyzer.Diagnostics() {
+		codes = append(codes, d.Code)
+		if d.Code == DiagDuplicateBackend && d.Module != "stacks/b" {
+			t.Errorf("duplicate_backend module = %s, want stacks/b", d.Module)
+		}
+	}
+	if !slices.Equal(codes, []DiagnosticCode{DiagDuplicateBackend}) {
+		t.Errorf("diagnostics = %v, want [%s]", codes, DiagDuplicateBackend)
+	}
+}
+
+// writeSyntheticRepo writes a repository with the shape of syntheticGraph to dir: every module
+// calls its dependencies through relative module sources.
+func writeSyntheticRepo(tb testing.TB, dir string, n int) (roots, changed []string) {
+	tb.Helper()
+	g, modules := syntheticGraph(n)
+	for _, module := range g.GetAllModules() {
+		var sb strings.Builder
+		for i, dep := range g.Dependencies(module) {
+			source, err := filepath.Rel(module, dep)
+			if err != nil {
+				tb.Fatal(err)
+			}
+			fmt.Fprintf(&sb, "module \"m%d\" {\n  source = \"./%s\"\n}\n\n", i, filepath.ToSlash(source))
+		}
+		if err := os.MkdirAll(filepath.Join(dir, module), 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, module, "main.tf"), []byte(sb.String()), 0o644); err != nil {
+			tb.Fatal(err)
+		}
+		if strings.HasPrefix(module, "environments/") {
+			roots = append(roots, module)
+		}
+	}
+	for _, module := range modules[:min(300, len(modules))] {
+		changed = append(changed, filepath.Join(module, "main.tf"))
+	}
+	return roots, changed
+}
+
+// BenchmarkAffectedRootModules measures AffectedRootModules, including causes and chains, for
+// many changed modules each affecting many root modules.
+func BenchmarkAffectedRootModules(b *testing.B) {
+	for _, n := range []int{1_000, 5_000} {
+		dir := b.TempDir()
+		roots, changed := writeSyntheticRepo(b, dir, n)
+		a := NewAnalyzer(dir)
+		if err := a.Analyze(b.Context()); err != nil {
+			b.Fatal(err)
+		}
+		isRoot := func(module string) bool {
+			return strings.HasPrefix(module, "environments/")
+		}
+
+		b.Run(fmt.Sprintf("modules=%d", n), func(b *testing.B) {
+			for b.Loop() {
+				modules, err := a.AffectedRootModules(changed, isRoot)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(modules) == 0 || len(modules) > len(roots) {
+					b.Fatalf("got %d affected root modules, want 1 to %d", len(modules), len(roots))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package tarm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+)
+
+// DefaultCacheDir is the directory the CLI and the action cache parsed modules in.
+const DefaultCacheDir = ".tarm-cache"
+
+// cacheFormatVersion is incremented whenever the cache entry format or what parsing extracts changes.
+const cacheFormatVersion = 3
+
+// moduleCache stores what parsing a module found, keyed by the module's path and the content of
+// its configuration files. Entries are written atomically and unreadable entries are treated as
+// missing, so the directory can be shared by concurrent runs and saved and restored by CI caches.
+type moduleCache struct {
+	dir string
+}
+
+// cacheEntry is a cached parsedModule. Paths within the root directory are stored relative to it.
+type cacheEntry struct {
+	ModuleCalls []cachedModuleCall `json:"module_calls,omitempty"`
+	References  []string           `json:"references,omitempty"`
+	// RootReferences are relative to the root module and stored as they are.
+	RootReferences []string        `json:"root_references,omitempty"`
+	Backend        *StateLocation  `json:"backend,omitempty"`
+	RemoteStates   []StateLocation `json:"remote_states,omitempty"`
+}
+
+type cachedModuleCall struct {
+	Name    string `json:"name"`
+	Source  string `json:"source"`
+	Version string `json:"version,omitempty"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+}
+
+// openModuleCache opens the cache in dir, creating it if needed. Entries written by another
+// version of tarm are removed.
+func openModuleCache(dir string) (*moduleCache, error) {
+	c := &moduleCache{dir: dir}
+	versionFile := filepath.Join(dir, "version")
+	version := cacheVersion()
+	if data, err := os.ReadFile(versionFile); err == nil && string(data) == version {
+		return c, nil
+	}
+
+	if err := os.RemoveAll(filepath.Join(dir, "modules")); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(versionFile, []byte(version)); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// cacheVersion identifies the entry format and the version of tarm that writes entries. The
+// version is that of the tarm module, not of the program embedding it, and is left out for
+// development builds, which rely on cacheFormatVersion alone.
+func cacheVersion() string {
+	info, _ := debug.ReadBuildInfo()
+	return cacheVersionFor(info)
+}
+
+func cacheVersionFor(info *debug.BuildInfo) string {
+	version := fmt.Sprintf("format=%d", cacheFormatVersion)
+	if v := moduleVersion(info); v != "" {
+		version += " version=" + v
+	}
+	return version
+}
+
+// key returns the cache key of the module in dir: a hash of its path relative to the root
+// directory, the loading mode and the names and content of the configuration files it loads.
+func (c *moduleCache) key(root, dir string, tofu bool) (string, error) {
+	relPath, err := filepath.Rel(root, dir)
+	if err != nil {
+		return "", err
+	}
+	files, err := configFiles(dir, tofu)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%t\x00", filepath.ToSlash(relPath), tofu)
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return "", err
+		}
+		info, err := f.Stat()
+		if err == nil {
+			fmt.Fprintf(h, "%s\x00%d\x00", filepath.Base(file), info.Size())
+			_, err = io.Copy(h, f)
+		}
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *moduleCache) path(key string) string {
+	return filepath.Join(c.dir, "modules", key[:2], key+".json")
+}
+
+// load returns the parsed module stored under key, or false if there is none.
+func (c *moduleCache) load(key, root, dir string) (*parsedModule, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, false
+	}
+
+	p := &parsedModule{path: dir, moduleCalls: make(map[string]*tfconfig.ModuleCall, len(e.ModuleCalls))}
+	for _, call := range e.ModuleCalls {
+		p.moduleCalls[call.Name] = &tfconfig.ModuleCall{
+			Name:    call.Name,
+			Source:  call.Source,
+			Version: call.Version,
+			Pos:     tfconfig.SourcePos{Filename: filepath.Join(dir, call.File), Line: call.Line},
+		}
+	}
+	for _, ref := range e.References {
+		p.references = append(p.references, fromCachePath(root, ref))
+	}
+	for _, ref := range e.RootReferences {
+		p.rootReferences = append(p.rootReferences, filepath.FromSlash(ref))
+	}
+	p.backend = e.Backend
+	p.remoteStates = e.RemoteStates
+	if p.backend != nil {
+		resolveCachedState(root, p.backend, fromCachePath)
+	}
+	for i := range p.remoteStates {
+		resolveCachedState(root, &p.remoteStates[i], fromCachePath)
+	}
+	return p, true
+}
+
+// store stores a parsed module under key. Modules that failed to parse are not stored, so
+// their errors are reported on every run.
+func (c *moduleCache) store(key, root string, p *parsedModule) error {
+	if p.parseErr != nil || p.bodiesErr != nil {
+		return nil
+	}
+
+	var e cacheEntry
+	for _, call := range p.moduleCalls {
+		e.ModuleCalls = append(e.ModuleCalls, cachedModuleCall{
+			Name:    call.Name,
+			Source:  call.Source,
+			Version: call.Version,
+			File:    filepath.Base(call.Pos.Filename),
+			Line:    call.Pos.Line,
+		})
+	}
+	sort.Slice(e.ModuleCalls, func(i, j int) bool {
+		return e.ModuleCalls[i].Name < e.ModuleCalls[j].Name
+	})
+	for _, ref := range p.references {
+		e.References = append(e.References, toCachePath(root, ref))
+	}
+	for _, ref := range p.rootReferences {
+		e.RootReferences = append(e.RootReferences, filepath.ToSlash(ref))
+	}
+	if p.backend != nil {
+		backend := *p.backend
+		resolveCachedState(root, &backend, toCachePath)
+		e.Backend = &backend
+	}
+	for _, state := range p.remoteStates {
+		resolveCachedState(root, &state, toCachePath)
+		e.RemoteStates = append(e.RemoteStates, state)
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// resolveCachedState converts the path of a local state with convert, copying its configuration.
+func resolveCachedState(root string, loc *StateLocation, convert func(root, path string) string) {
+	if loc.Backend != "local" {
+		return
+	}
+	config := make(map[string]string, len(loc.Config))
+	for k, v := range loc.Config {
+		config[k] = v
+	}
+	config["path"] = convert(root, config["path"])
+	loc.Config = config
+}
+
+// toCachePath makes an absolute path within the root directory relative to it, so that entries
+// stay valid when the repository is checked out elsewhere. Other paths are kept absolute.
+func toCachePath(root, path string) string {
+	if !IsWithinDirectory(path, root) {
+		return path
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+func fromCachePath(root, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(root, filepath.FromSlash(path))
+}
+
+// writeFileAtomic writes data to path through a temporary file, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
@@ -0,0 +1,175 @@
+package tarm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzer_Cache(t *testing.T) {
+	roots := []string{"terraform", "terraform-assets", "terraform-parse-errors", "terraform-remote-state", "terragrunt", "opentofu"}
+
+	for _, root := range roots {
+		t.Run(root, func(t *testing.T) {
+			testRoot := filepath.Join("..", "..", "testdata", root)
+			cacheDir := t.TempDir()
+
+			uncached := NewAnalyzer(testRoot)
+			if err := uncached.Analyze(t.Context()); err != nil {
+				t.Fatalf("Analyze() failed: %v", err)
+			}
+			want := analysisSnapshot(uncached)
+
+			for _, run := range []string{"cold", "warm"} {
+				a := NewAnalyzer(testRoot, WithCache(cacheDir))
+				if err := a.Analyze(t.Context()); err != nil {
+					t.Fatalf("%s: Analyze() failed: %v", run, err)
+				}
+				if got := analysisSnapshot(a); got != want {
+					t.Errorf("%s: cached analysis differs:\ngot  %s\nwant %s", run, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_CacheInvalidation(t *testing.T) {
+	root := t.TempDir()
+	if err := os.CopyFS(root, os.DirFS(filepath.Join("..", "..", "testdata", "terraform"))); err != nil {
+		t.Fatal(err)
+	}
+	cacheDir := filepath.Join(root, DefaultCacheDir)
+	mainTf := filepath.Join(root, "environments", "dev", "api", "main.tf")
+
+	analyze := func() []ModuleCall {
+		t.Helper()
+		a := NewAnalyzer(root, WithCache(cacheDir))
+		if err := a.Analyze(t.Context()); err != nil {
+			t.Fatalf("Analyze() failed: %v", err)
+		}
+		for _, module := range a.modules {
+			if strings.HasPrefix(module, DefaultCacheDir) {
+				t.Errorf("cache directory analyzed as module %s", module)
+			}
+		}
+		var calls []ModuleCall
+		for _, call := range a.GetDependencyGraph().ModuleCalls() {
+			if call.Module == filepath.Join("environments", "dev", "api") {
+				calls = append(calls, call)
+			}
+		}
+		return calls
+	}
+	// tamper replaces the source of every cached module call, so that reading an entry shows.
+	tamper := func() {
+		t.Helper()
+		entries, _ := filepath.Glob(filepath.Join(cacheDir, "modules", "*", "*.json"))
+		if len(entries) == 0 {
+			t.Fatal("no cache entries written")
+		}
+		for _, entry := range entries {
+			data, err := os.ReadFile(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data = []byte(strings.ReplaceAll(string(data), `"source":"`, `"source":"cached/`))
+			if err := os.WriteFile(entry, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	isCached := func(calls []ModuleCall) bool {
+		return len(calls) > 0 && strings.HasPrefix(calls[0].Source.Raw, "cached/")
+	}
+
+	if calls := analyze(); len(calls) == 0 || isCached(calls) {
+		t.Fatalf("first analysis = %v, want parsed module calls", calls)
+	}
+	tamper()
+	if calls := analyze(); !isCached(calls) {
+		t.Errorf("unchanged module = %v, want it read from the cache", calls)
+	}
+
+	content, err := os.ReadFile(mainTf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(mainTf, append(content, "\n# changed\n"...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if calls := analyze(); isCached(calls) {
+		t.Errorf("changed module = %v, want it parsed again", calls)
+	}
+
+	tamper()
+	if err := os.WriteFile(filepath.Join(cacheDir, "version"), []byte("format=0"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if calls := analyze(); isCached(calls) {
+		t.Errorf("module cached by another version = %v, want it parsed again", calls)
+	}
+}
+
+func TestCacheVersionFor(t *testing.T) {
+	format := fmt.Sprintf("format=%d", cacheFormatVersion)
+
+	tests := []struct {
+		name string
+		info *debug.BuildInfo
+		want string
+	}{
+		{name: "no build info", info: nil, want: format},
+		{name: "tarm binary", info: &debug.BuildInfo{Main: debug.Module{Path: modulePath, Version: "v1.2.0"}}, want: format + " version=v1.2.0"},
+		{name: "development build", info: &debug.BuildInfo{Main: debug.Module{Path: modulePath, Version: "(devel)"}}, want: format},
+		{
+			name: "library in another program",
+			info: &debug.BuildInfo{
+				Main: debug.Module{Path: "example.com/ci", Version: "v9.0.0"},
+				Deps: []*debug.Module{{Path: "github.com/hashicorp/hcl/v2", Version: "v2.23.0"}, {Path: modulePath, Version: "v1.2.0"}},
+			},
+			want: format + " version=v1.2.0",
+		},
+		{
+			name: "replaced library",
+			info: &debug.BuildInfo{
+				Main: debug.Module{Path: "example.com/ci", Version: "v9.0.0"},
+				Deps: []*debug.Module{{Path: modulePath, Version: "v1.2.0", Replace: &debug.Module{Path: "../tarm"}}},
+			},
+			want: format,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cacheVersionFor(tt.info); got != tt.want {
+				t.Errorf("cacheVersionFor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCachePath(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+
+	tests := []struct {
+		path   string
+		cached string
+	}{
+		{path: "/repo/modules/vpc/files/a.json", cached: "modules/vpc/files/a.json"},
+		{path: "/repo", cached: "."},
+		{path: "/var/state/terraform.tfstate", cached: "/var/state/terraform.tfstate"},
+	}
+	for _, tt := range tests {
+		path := filepath.FromSlash(tt.path)
+		cached := toCachePath(root, path)
+		if cached != tt.cached {
+			t.Errorf("toCachePath(%q) = %q, want %q", path, cached, tt.cached)
+		}
+		if got := fromCachePath(root, cached); got != path {
+			t.Errorf("fromCachePath(%q) = %q, want %q", cached, got, path)
+		}
+	}
+}
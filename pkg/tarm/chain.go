@@ -0,0 +1,243 @@
+package tarm
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Chain is a path through the dependency graph from an affected root module to the changed path
+// that affects it.
+type Chain struct {
+	// Cause is the changed path the chain ends at.
+	Cause string      `json:"cause"`
+	Steps []ChainStep `json:"steps"`
+}
+
+// ChainStep is one edge of a chain.
+type ChainStep struct {
+	From string   `json:"from"`
+	To   string   `json:"to"`
+	Kind EdgeKind `json:"kind"`
+	// Block names the block creating a module call edge, e.g. "module.database", or
+	// "terraform" for the source of a Terragrunt unit. File and Line locate it.
+	Block string `json:"block,omitempty"`
+	File  string `json:"file,omitempty"`
+	Line  int    `json:"line,omitempty"`
+}
+
+// String renders the chain as "root -> module.name (file:line) -> module -> ... -> cause".
+func (c Chain) String() string {
+	if len(c.Steps) == 0 {
+		return c.Cause
+	}
+
+	var sb strings.Builder
+	sb.WriteString(c.Steps[0].From)
+	for _, step := range c.Steps {
+		sb.WriteString(" -> ")
+		switch {
+		case step.Block != "" && step.File != "" && step.Line > 0:
+			fmt.Fprintf(&sb, "%s (%s:%d) -> ", step.Block, step.File, step.Line)
+		case step.Block != "" && step.File != "":
+			fmt.Fprintf(&sb, "%s (%s) -> ", step.Block, step.File)
+		case step.Kind != EdgeModuleCall:
+			fmt.Fprintf(&sb, "[%s] -> ", step.Kind)
+		}
+		sb.WriteString(step.To)
+	}
+	return sb.String()
+}
+
+// ShortestPath returns the shortest sequence of modules from 'from' to 'to' following
+// dependency edges, including both ends, or nil if 'to' is not reachable. Ties are broken
+// by visiting dependencies in sorted order, so the result is deterministic.
+func (g *DependencyGraph) ShortestPath(from, to string) []string {
+	from = filepath.Clean(from)
+	to = filepath.Clean(to)
+
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == to {
+			var path []string
+			for node := to; node != ""; node = prev[node] {
+				path = append(path, node)
+			}
+			slices.Reverse(path)
+			return path
+		}
+
+		for _, dep := range g.Dependencies(current) {
+			if _, seen := prev[dep]; !seen {
+				prev[dep] = current
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return nil
+}
+
+// ShortestPaths returns every shortest sequence of modules from 'from' to 'to' following
+// dependency edges, including both ends, in lexical order of their modules. It returns nil
+// if 'to' is not reachable.
+func (g *DependencyGraph) ShortestPaths(from, to string) [][]string {
+	from = filepath.Clean(from)
+	to = filepath.Clean(to)
+
+	// Breadth-first search recording every predecessor on a shortest path.
+	dist := map[string]int{from: 0}
+	prevs := make(map[string][]string)
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if d, ok := dist[to]; ok && dist[current] >= d {
+			break
+		}
+
+		for _, dep := range g.Dependencies(current) {
+			d, seen := dist[dep]
+			if !seen {
+				dist[dep] = dist[current] + 1
+				queue = append(queue, dep)
+			} else if d != dist[current]+1 {
+				continue
+			}
+			prevs[dep] = append(prevs[dep], current)
+		}
+	}
+	if _, ok := dist[to]; !ok {
+		return nil
+	}
+
+	var paths [][]string
+	var walk func(node string, suffix []string)
+	walk = func(node string, suffix []string) {
+		suffix = append([]string{node}, suffix...)
+		if node == from {
+			paths = append(paths, suffix)
+			return
+		}
+		for _, prev := range prevs[node] {
+			walk(prev, suffix)
+		}
+	}
+	walk(to, nil)
+
+	slices.SortFunc(paths, func(a, b []string) int {
+		return slices.Compare(a, b)
+	})
+	return paths
+}
+
+// Chain returns the shortest chain from the module 'from' to the changed path 'cause', with the
+// position of the block behind every module call edge, or false if 'cause' is not reachable.
+func (g *DependencyGraph) Chain(from, cause string) (Chain, bool) {
+	chain, ok := g.ChainsFrom([]string{from}, cause)[filepath.Clean(from)]
+	return chain, ok
+}
+
+// ChainsFrom returns the shortest chain from each of the given modules to the changed path
+// 'cause', keyed by module. Modules that do not reach 'cause' are left out. The graph is
+// traversed once from 'cause', so the chains of many modules cost a single traversal. Ties are
+// broken by following the dependency that sorts first.
+func (g *DependencyGraph) ChainsFrom(modules []string, cause string) map[string]Chain {
+	cause = filepath.Clean(cause)
+	chains := make(map[string]Chain)
+	causeID, ok := g.ids[cause]
+	if !ok {
+		if slices.Contains(modules, cause) {
+			chains[cause] = g.chain(cause, []string{cause})
+		}
+		return chains
+	}
+
+	dist := g.distancesTo(causeID)
+	for _, from := range modules {
+		from = filepath.Clean(from)
+		id, ok := g.ids[from]
+		if !ok || dist[id] < 0 {
+			continue
+		}
+		path := []string{from}
+		for id != causeID {
+			next := -1
+			for _, dep := range g.out[id] {
+				if dist[dep] == dist[id]-1 && (next < 0 || g.names[dep] < g.names[next]) {
+					next = dep
+				}
+			}
+			id = next
+			path = append(path, g.names[id])
+		}
+		chains[from] = g.chain(cause, path)
+	}
+	return chains
+}
+
+// distancesTo returns the length of the shortest path from every node to the node 'to',
+// or -1 for nodes that do not reach it.
+func (g *DependencyGraph) distancesTo(to int) []int {
+	dist := make([]int, len(g.names))
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[to] = 0
+	queue := []int{to}
+	for i := 0; i < len(queue); i++ {
+		current := queue[i]
+		for _, dependent := range g.in[current] {
+			if dist[dependent] < 0 {
+				dist[dependent] = dist[current] + 1
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	return dist
+}
+
+// Chains returns every shortest chain from the module 'from' to the changed path 'cause'.
+func (g *DependencyGraph) Chains(from, cause string) []Chain {
+	paths := g.ShortestPaths(from, cause)
+	chains := make([]Chain, 0, len(paths))
+	for _, path := range paths {
+		chains = append(chains, g.chain(cause, path))
+	}
+	return chains
+}
+
+// chain builds the chain along a path of modules ending at 'cause'.
+func (g *DependencyGraph) chain(cause string, path []string) Chain {
+	chain := Chain{Cause: cause, Steps: make([]ChainStep, 0, len(path)-1)}
+	for i := 1; i < len(path); i++ {
+		step := ChainStep{From: path[i-1], To: path[i], Kind: g.Kind(path[i-1], path[i])}
+		if call, ok := g.ModuleCallFor(step.From, step.To); ok && step.Kind == EdgeModuleCall {
+			step.Block = "module." + call.Name
+			if filepath.Base(call.File) == TerragruntConfigFile {
+				step.Block = call.Name
+			}
+			step.File = call.File
+			step.Line = call.Line
+		}
+		chain.Steps = append(chain.Steps, step)
+	}
+	return chain
+}
+
+// ModuleCallFor returns the first recorded module call in 'from' that resolves to the module 'to'.
+func (g *DependencyGraph) ModuleCallFor(from, to string) (ModuleCall, bool) {
+	f, ok := g.ids[filepath.Clean(from)]
+	if !ok {
+		return ModuleCall{}, false
+	}
+	t, ok := g.ids[filepath.Clean(to)]
+	if !ok {
+		return ModuleCall{}, false
+	}
+	call, ok := g.callIndex[[2]int{f, t}]
+	return call, ok
+}
@@ -0,0 +1,157 @@
+package tarm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShortestPath(t *testing.T) {
+	g := NewDependencyGraph()
+	g.AddDependency("root", "b")
+	g.AddDependency("root", "a")
+	g.AddDependency("a", "c")
+	g.AddDependency("b", "c")
+	g.AddDependency("c", "d")
+	g.AddDependency("root", "d/e")
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     []string
+	}{
+		{name: "same node", from: "root", to: "root", want: []string{"root"}},
+		{name: "direct edge", from: "root", to: "d/e", want: []string{"root", "d/e"}},
+		{name: "ties are broken in sorted order", from: "root", to: "d", want: []string{"root", "a", "c", "d"}},
+		{name: "unreachable", from: "c", to: "root", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := g.ShortestPath(tt.from, tt.to)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ShortestPath(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortestPaths(t *testing.T) {
+	g := NewDependencyGraph()
+	g.AddDependency("root", "b")
+	g.AddDependency("root", "a")
+	g.AddDependency("a", "c")
+	g.AddDependency("b", "c")
+	g.AddDependency("c", "d")
+	g.AddDependency("root", "e")
+	g.AddDependency("e", "f")
+	g.AddDependency("f", "d")
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     [][]string
+	}{
+		{name: "same node", from: "root", to: "root", want: [][]string{{"root"}}},
+		{name: "single path", from: "root", to: "a", want: [][]string{{"root", "a"}}},
+		{name: "every shortest path in sorted order", from: "root", to: "d", want: [][]string{
+			{"root", "a", "c", "d"},
+			{"root", "b", "c", "d"},
+			{"root", "e", "f", "d"},
+		}},
+		{name: "unreachable", from: "c", to: "root", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := g.ShortestPaths(tt.from, tt.to)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ShortestPaths(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	g := NewDependencyGraph()
+	g.AddDependency("envs/api", "modules/db")
+	g.AddModuleCall(ModuleCall{Module: "envs/api", Name: "db", Local: "modules/db", File: "envs/api/main.tf", Line: 3})
+	g.AddDependencyKind("modules/db", "schemas/db.sql", EdgeFileReference)
+	g.AddDependencyKind("envs/web", "envs/api", EdgeRemoteState)
+	g.AddDependency("live/app", "modules/db")
+	g.AddModuleCall(ModuleCall{Module: "live/app", Name: "terraform", Local: "modules/db", File: "live/app/terragrunt.hcl"})
+
+	tests := []struct {
+		name       string
+		from       string
+		cause      string
+		wantString string
+		wantSteps  int
+	}{
+		{
+			name:       "module call with position",
+			from:       "envs/api",
+			cause:      "modules/db",
+			wantString: "envs/api -> module.db (envs/api/main.tf:3) -> modules/db",
+			wantSteps:  1,
+		},
+		{
+			name:       "remote state and file reference",
+			from:       "envs/web",
+			cause:      "schemas/db.sql",
+			wantString: "envs/web -> [remote_state] -> envs/api -> module.db (envs/api/main.tf:3) -> modules/db -> [file] -> schemas/db.sql",
+			wantSteps:  3,
+		},
+		{
+			name:       "terragrunt source",
+			from:       "live/app",
+			cause:      "modules/db",
+			wantString: "live/app -> terraform (live/app/terragrunt.hcl) -> modules/db",
+			wantSteps:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain, ok := g.Chain(tt.from, tt.cause)
+			if !ok {
+				t.Fatalf("Chain(%q, %q) not found", tt.from, tt.cause)
+			}
+			if len(chain.Steps) != tt.wantSteps {
+				t.Errorf("got %d steps, want %d", len(chain.Steps), tt.wantSteps)
+			}
+			if got := chain.String(); got != tt.wantString {
+				t.Errorf("String() = %q, want %q", got, tt.wantString)
+			}
+		})
+	}
+
+	if _, ok := g.Chain("modules/db", "envs/api"); ok {
+		t.Error("Chain() found a chain against edge direction")
+	}
+}
+
+func TestChainsFrom(t *testing.T) {
+	g := NewDependencyGraph()
+	g.AddDependency("envs/a", "modules/app")
+	g.AddDependency("envs/a", "modules/b")
+	g.AddDependency("envs/a", "modules/a")
+	g.AddDependency("modules/b", "modules/db")
+	g.AddDependency("modules/a", "modules/db")
+	g.AddDependency("modules/app", "modules/a")
+	g.AddDependency("envs/b", "modules/db")
+	g.AddModuleCall(ModuleCall{Module: "envs/b", Name: "db_replica", Local: "modules/db", File: "envs/b/main.tf", Line: 9})
+	g.AddModuleCall(ModuleCall{Module: "envs/b", Name: "db", Local: "modules/db", File: "envs/b/main.tf", Line: 2})
+
+	got := make(map[string]string)
+	for module, chain := range g.ChainsFrom([]string{"envs/a", "envs/b", "envs/c", "modules/db"}, "modules/db") {
+		got[module] = chain.String()
+	}
+	want := map[string]string{
+		"envs/a":     "envs/a -> modules/a -> modules/db",
+		"envs/b":     "envs/b -> module.db (envs/b/main.tf:2) -> modules/db",
+		"modules/db": "modules/db",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ChainsFrom() = %q, want %q", got, want)
+	}
+}
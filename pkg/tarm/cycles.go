@@ -0,0 +1,226 @@
+package tarm
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Cycle is a strongly connected component of the dependency graph: modules that depend on
+// each other, directly or transitively.
+type Cycle struct {
+	// Modules are the members of the component, sorted.
+	Modules []string `json:"modules"`
+	// Steps are a shortest cycle through the component, starting and ending at its first module,
+	// with the position of the block behind every module call edge.
+	Steps []ChainStep `json:"steps"`
+	// Edges are every edge between modules of the component, sorted by their modules, so that
+	// all the module calls to change to break the cycles are known.
+	Edges []ChainStep `json:"edges"`
+}
+
+// String renders the cycle as "a -> module.b (file:line) -> b -> module.a (file:line) -> a".
+func (c Cycle) String() string {
+	return Chain{Cause: c.Modules[0], Steps: c.Steps}.String()
+}
+
+// CycleError is returned when dependency cycles are found and the config asks to fail on them.
+type CycleError struct {
+	Cycles []Cycle
+}
+
+func (e *CycleError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "found %d dependency cycle(s):", len(e.Cycles))
+	for _, c := range e.Cycles {
+		fmt.Fprintf(&sb, "\n  %s", c)
+		for _, edge := range c.Edges {
+			if !slices.Contains(c.Steps, edge) {
+				fmt.Fprintf(&sb, "\n    also %s", Chain{Steps: []ChainStep{edge}})
+			}
+		}
+	}
+	return sb.String()
+}
+
+// StronglyConnectedComponents returns the strongly connected components of the graph that contain
+// a cycle, i.e. more than one module or a module depending on itself. Modules are sorted within
+// each component, and components are sorted by their first module.
+func (g *DependencyGraph) StronglyConnectedComponents() [][]string {
+	var components [][]string
+	for _, component := range g.components(g.sortedAdjacency()) {
+		components = append(components, g.componentNames(component))
+	}
+	return components
+}
+
+// components returns the strongly connected components of the graph that contain a cycle, as
+// sorted module IDs, sorted by the name of their first module. adj is g.sortedAdjacency().
+func (g *DependencyGraph) components(adj [][]int) [][]int {
+	// Tarjan's algorithm, visiting modules and their dependencies in sorted order.
+	index := make([]int, len(g.names))
+	lowLink := make([]int, len(g.names))
+	onStack := make([]bool, len(g.names))
+	for i := range index {
+		index[i] = -1
+	}
+	visited := 0
+	var stack []int
+	var components [][]int
+
+	var strongConnect func(int)
+	strongConnect = func(v int) {
+		index[v] = visited
+		lowLink[v] = visited
+		visited++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range adj[v] {
+			if index[w] < 0 {
+				strongConnect(w)
+				lowLink[v] = min(lowLink[v], lowLink[w])
+			} else if onStack[w] {
+				lowLink[v] = min(lowLink[v], index[w])
+			}
+		}
+
+		if lowLink[v] != index[v] {
+			return
+		}
+		var component []int
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			component = append(component, w)
+			if w == v {
+				break
+			}
+		}
+		if _, selfLoop := g.edges[edgeKey(v, v)]; len(component) > 1 || selfLoop {
+			g.sortByName(component)
+			components = append(components, component)
+		}
+	}
+
+	modules := make([]int, len(g.names))
+	for i := range modules {
+		modules[i] = i
+	}
+	g.sortByName(modules)
+	for _, module := range modules {
+		if index[module] < 0 {
+			strongConnect(module)
+		}
+	}
+
+	sort.Slice(components, func(i, j int) bool {
+		return g.names[components[i][0]] < g.names[components[j][0]]
+	})
+	return components
+}
+
+// sortedAdjacency returns the dependencies of every module sorted by name, so that traversals
+// visiting them in sorted order do not sort them again on every visit.
+func (g *DependencyGraph) sortedAdjacency() [][]int {
+	adj := make([][]int, len(g.out))
+	for i, deps := range g.out {
+		adj[i] = slices.Clone(deps)
+		g.sortByName(adj[i])
+	}
+	return adj
+}
+
+func (g *DependencyGraph) sortByName(ids []int) {
+	sort.Slice(ids, func(i, j int) bool {
+		return g.names[ids[i]] < g.names[ids[j]]
+	})
+}
+
+func (g *DependencyGraph) componentNames(component []int) []string {
+	names := make([]string, len(component))
+	for i, id := range component {
+		names[i] = g.names[id]
+	}
+	return names
+}
+
+// Cycles returns a cycle for every strongly connected component of the graph that contains one,
+// with every edge within the component.
+func (g *DependencyGraph) Cycles() []Cycle {
+	adj := g.sortedAdjacency()
+	var cycles []Cycle
+	for _, component := range g.components(adj) {
+		modules := g.componentNames(component)
+		path := g.shortestCycle(adj, component)
+		cycle := Cycle{Modules: modules, Steps: g.chain(modules[0], path).Steps}
+
+		inComponent := make(map[int]bool, len(component))
+		for _, id := range component {
+			inComponent[id] = true
+		}
+		for _, id := range component {
+			for _, dep := range adj[id] {
+				if inComponent[dep] {
+					cycle.Edges = append(cycle.Edges, g.chain(g.names[dep], []string{g.names[id], g.names[dep]}).Steps...)
+				}
+			}
+		}
+		cycles = append(cycles, cycle)
+	}
+	return cycles
+}
+
+// DetectCircularDependencies returns a shortest cycle through every strongly connected component
+// of the graph, starting at the component's first module in sorted order and not repeating it.
+func (g *DependencyGraph) DetectCircularDependencies() [][]string {
+	adj := g.sortedAdjacency()
+	var cycles [][]string
+	for _, component := range g.components(adj) {
+		path := g.shortestCycle(adj, component)
+		cycles = append(cycles, path[:len(path)-1])
+	}
+	return cycles
+}
+
+// shortestCycle returns a shortest path from the first module of the component back to itself,
+// staying within the component. Ties are broken by visiting dependencies in sorted order.
+func (g *DependencyGraph) shortestCycle(adj [][]int, component []int) []string {
+	start := component[0]
+	if _, selfLoop := g.edges[edgeKey(start, start)]; selfLoop {
+		return []string{g.names[start], g.names[start]}
+	}
+
+	inComponent := make(map[int]bool, len(component))
+	for _, module := range component {
+		inComponent[module] = true
+	}
+
+	prev := make(map[int]int)
+	queue := []int{start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dep := range adj[current] {
+			if !inComponent[dep] {
+				continue
+			}
+			if dep == start {
+				path := []string{g.names[start]}
+				for node := current; node != start; node = prev[node] {
+					path = append(path, g.names[node])
+				}
+				path = append(path, g.names[start])
+				slices.Reverse(path)
+				return path
+			}
+			if _, seen := prev[dep]; !seen {
+				prev[dep] = current
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return []string{g.names[start], g.names[start]}
+}
@@ -0,0 +1,80 @@
+package tarm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStronglyConnectedComponents(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges [][2]string
+		want  [][]string
+	}{
+		{name: "no cycles", edges: [][2]string{{"a", "b"}, {"b", "c"}}, want: nil},
+		{name: "self-loop", edges: [][2]string{{"a", "a"}, {"a", "b"}}, want: [][]string{{"a"}}},
+		{
+			name:  "every component in sorted order",
+			edges: [][2]string{{"z", "y"}, {"y", "z"}, {"root", "c"}, {"c", "b"}, {"b", "a"}, {"a", "c"}, {"root", "z"}},
+			want:  [][]string{{"a", "b", "c"}, {"y", "z"}},
+		},
+		{
+			name:  "nested cycles form one component",
+			edges: [][2]string{{"a", "b"}, {"b", "a"}, {"b", "c"}, {"c", "b"}},
+			want:  [][]string{{"a", "b", "c"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Repeat to catch any dependence on map iteration order.
+			for range 10 {
+				g := NewDependencyGraph()
+				for _, e := range tt.edges {
+					g.AddDependency(e[0], e[1])
+				}
+				if got := g.StronglyConnectedComponents(); !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("StronglyConnectedComponents() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCycles(t *testing.T) {
+	g := NewDependencyGraph()
+	g.AddDependency("modules/c", "modules/a")
+	g.AddDependency("modules/a", "modules/b")
+	g.AddDependency("modules/b", "modules/c")
+	g.AddDependency("modules/b", "modules/a")
+	g.AddModuleCall(ModuleCall{Module: "modules/a", Name: "b", Local: "modules/b", File: "modules/a/main.tf", Line: 1})
+	g.AddModuleCall(ModuleCall{Module: "modules/b", Name: "a", Local: "modules/a", File: "modules/b/main.tf", Line: 5})
+	g.AddDependencyKind("envs/web", "envs/api", EdgeRemoteState)
+	g.AddDependencyKind("envs/api", "envs/web", EdgeRemoteState)
+
+	want := []string{
+		"envs/api -> [remote_state] -> envs/web -> [remote_state] -> envs/api",
+		"modules/a -> module.b (modules/a/main.tf:1) -> modules/b -> module.a (modules/b/main.tf:5) -> modules/a",
+	}
+	var got []string
+	for _, c := range g.Cycles() {
+		got = append(got, c.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Cycles() = %q, want %q", got, want)
+	}
+
+	wantErr := `found 2 dependency cycle(s):
+  envs/api -> [remote_state] -> envs/web -> [remote_state] -> envs/api
+  modules/a -> module.b (modules/a/main.tf:1) -> modules/b -> module.a (modules/b/main.tf:5) -> modules/a
+    also modules/b -> modules/c
+    also modules/c -> modules/a`
+	if got := (&CycleError{Cycles: g.Cycles()}).Error(); got != wantErr {
+		t.Errorf("CycleError.Error() = %q, want %q", got, wantErr)
+	}
+
+	wantPaths := [][]string{{"envs/api", "envs/web"}, {"modules/a", "modules/b"}}
+	if got := g.DetectCircularDependencies(); !reflect.DeepEqual(got, wantPaths) {
+		t.Errorf("DetectCircularDependencies() = %v, want %v", got, wantPaths)
+	}
+}
@@ -0,0 +1,150 @@
+package tarm
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+)
+
+// Severity is the severity of a diagnostic.
+type Severity string
+
+const (
+	// SeverityWarning marks a condition the analysis worked around.
+	SeverityWarning Severity = "warning"
+	// SeverityError marks a condition that fails the run.
+	SeverityError Severity = "error"
+)
+
+// DiagnosticCode identifies the condition a diagnostic reports.
+type DiagnosticCode string
+
+const (
+	// DiagParseError reports a module or Terragrunt configuration that failed to parse.
+	DiagParseError DiagnosticCode = "parse_error"
+	// DiagScanError reports a module whose module calls could not be recovered after a parse error.
+	DiagScanError DiagnosticCode = "scan_error"
+	// DiagReadError reports a module whose configuration files could not be read.
+	DiagReadError DiagnosticCode = "read_error"
+	// DiagInvalidModuleSource reports a module source that could not be resolved.
+	DiagInvalidModuleSource DiagnosticCode = "invalid_module_source"
+	// DiagModuleSourceNotFound reports a local module source pointing to a directory that does not exist.
+	DiagModuleSourceNotFound DiagnosticCode = "module_source_not_found"
+	// DiagTerragruntPathNotFound reports a Terragrunt source, dependency or include that does not exist.
+	DiagTerragruntPathNotFound DiagnosticCode = "terragrunt_path_not_found"
+	// DiagPathError reports a path that could not be related to the root directory.
+	DiagPathError DiagnosticCode = "path_error"
+	// DiagDependencyCycle reports modules that depend on each other.
+	DiagDependencyCycle DiagnosticCode = "dependency_cycle"
+	// DiagCacheError reports a cache directory that could not be used.
+	DiagCacheError DiagnosticCode = "cache_error"
+	// DiagDuplicateBackend reports modules storing their state in the same backend location.
+	DiagDuplicateBackend DiagnosticCode = "duplicate_backend"
+)
+
+// DiagnosticCodes lists every diagnostic code.
+var DiagnosticCodes = []DiagnosticCode{
+	DiagParseError,
+	DiagScanError,
+	DiagReadError,
+	DiagInvalidModuleSource,
+	DiagModuleSourceNotFound,
+	DiagTerragruntPathNotFound,
+	DiagPathError,
+	DiagDependencyCycle,
+	DiagCacheError,
+	DiagDuplicateBackend,
+}
+
+// ParseDiagnosticCodes parses diagnostic codes given as a list of comma separated values.
+func ParseDiagnosticCodes(values []string) ([]DiagnosticCode, error) {
+	var codes []DiagnosticCode
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			code := DiagnosticCode(s)
+			if !slices.Contains(DiagnosticCodes, code) {
+				return nil, fmt.Errorf("unknown diagnostic code %q", s)
+			}
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
+
+// Diagnostic is a condition found during analysis. File is relative to the root directory.
+type Diagnostic struct {
+	Code     DiagnosticCode `json:"code"`
+	Severity Severity       `json:"severity"`
+	Message  string         `json:"message"`
+	File     string         `json:"file,omitempty"`
+	Line     int            `json:"line,omitempty"`
+	Module   string         `json:"module,omitempty"`
+}
+
+// String renders the diagnostic as "file:line: message [code]".
+func (d Diagnostic) String() string {
+	var sb strings.Builder
+	switch {
+	case d.File != "" && d.Line > 0:
+		fmt.Fprintf(&sb, "%s:%d: ", d.File, d.Line)
+	case d.File != "":
+		fmt.Fprintf(&sb, "%s: ", d.File)
+	}
+	fmt.Fprintf(&sb, "%s [%s]", d.Message, d.Code)
+	return sb.String()
+}
+
+// DiagnosticError is returned when diagnostics are reported that the config asks to fail on.
+type DiagnosticError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *DiagnosticError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "found %d failing diagnostic(s):", len(e.Diagnostics))
+	for _, d := range e.Diagnostics {
+		fmt.Fprintf(&sb, "\n  %s", d)
+	}
+	return sb.String()
+}
+
+// failingDiagnostics returns the diagnostics that fail the run, as errors: all of them in strict
+// mode, and otherwise those whose code is listed in failOn.
+func failingDiagnostics(diags []Diagnostic, strict bool, failOn []DiagnosticCode) []Diagnostic {
+	var failing []Diagnostic
+	for _, d := range diags {
+		if strict || slices.Contains(failOn, d.Code) {
+			d.Severity = SeverityError
+			failing = append(failing, d)
+		}
+	}
+	return failing
+}
+
+// diagnosticPosition returns the position of the first error reported by tfconfig, relative to
+// the root directory.
+func diagnosticPosition(root string, err error) (string, int) {
+	var diags tfconfig.Diagnostics
+	if !errors.As(err, &diags) {
+		return "", 0
+	}
+	for _, diag := range diags {
+		if diag.Severity != tfconfig.DiagError || diag.Pos == nil {
+			continue
+		}
+		file, err := filepath.Rel(root, diag.Pos.Filename)
+		if err != nil {
+			return "", 0
+		}
+		return file, diag.Pos.Line
+	}
+	return "", 0
+}
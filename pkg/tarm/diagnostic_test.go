@@ -0,0 +1,112 @@
+package tarm
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseDiagnosticCodes(t *testing.T) {
+	tests := []struct {
+		values  []string
+		want    []DiagnosticCode
+		wantErr bool
+	}{
+		{values: nil, want: nil},
+		{values: []string{"parse_error"}, want: []DiagnosticCode{DiagParseError}},
+		{values: []string{"parse_error, module_source_not_found", "dependency_cycle"}, want: []DiagnosticCode{DiagParseError, DiagModuleSourceNotFound, DiagDependencyCycle}},
+		{values: []string{"parse-error"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDiagnosticCodes(tt.values)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDiagnosticCodes(%q) error = %v, wantErr %v", tt.values, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseDiagnosticCodes(%q) = %v, want %v", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestDiagnostic_String(t *testing.T) {
+	tests := []struct {
+		d    Diagnostic
+		want string
+	}{
+		{
+			d:    Diagnostic{Code: DiagModuleSourceNotFound, Message: "module source not found", File: "app/main.tf", Line: 2},
+			want: "app/main.tf:2: module source not found [module_source_not_found]",
+		},
+		{
+			d:    Diagnostic{Code: DiagParseError, Message: "failed to parse", File: "app/terragrunt.hcl"},
+			want: "app/terragrunt.hcl: failed to parse [parse_error]",
+		},
+		{
+			d:    Diagnostic{Code: DiagCacheError, Message: "failed to open cache"},
+			want: "failed to open cache [cache_error]",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.d.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestRun_FailOnDiagnostics(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform-errors")
+
+	tests := []struct {
+		name      string
+		strict    bool
+		failOn    []DiagnosticCode
+		wantCodes []DiagnosticCode
+	}{
+		{name: "warnings only"},
+		{name: "fail on a reported code", failOn: []DiagnosticCode{DiagModuleSourceNotFound}, wantCodes: []DiagnosticCode{DiagModuleSourceNotFound}},
+		{name: "fail on an unreported code", failOn: []DiagnosticCode{DiagCacheError}},
+		{name: "strict", strict: true, wantCodes: []DiagnosticCode{DiagParseError, DiagModuleSourceNotFound, DiagDependencyCycle}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Root:               testRoot,
+				RootModulePatterns: []string{"*"},
+				ChangedFiles:       []string{"invalid-syntax/main.tf"},
+				Strict:             tt.strict,
+				FailOn:             tt.failOn,
+			}
+			result, err := Run(t.Context(), cfg, nil)
+
+			if len(tt.wantCodes) == 0 {
+				if err != nil {
+					t.Fatalf("Run() error = %v", err)
+				}
+				if len(result.Diagnostics) == 0 {
+					t.Error("Result.Diagnostics is empty")
+				}
+				return
+			}
+
+			var diagErr *DiagnosticError
+			if !errors.As(err, &diagErr) {
+				t.Fatalf("Run() error = %v, want *DiagnosticError", err)
+			}
+			var codes []DiagnosticCode
+			for _, d := range diagErr.Diagnostics {
+				codes = append(codes, d.Code)
+				if d.Severity != SeverityError {
+					t.Errorf("%s severity = %s, want %s", d.Code, d.Severity, SeverityError)
+				}
+			}
+			if !reflect.DeepEqual(codes, tt.wantCodes) {
+				t.Errorf("failing codes = %v, want %v", codes, tt.wantCodes)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+// Package tarm finds the Terraform root modules affected by a set of changed files.
+//
+// Run analyzes a repository with a Config and returns the affected root modules with the
+// changes and dependency chains that affect them. Analyzer and DependencyGraph give direct
+// access to the module dependency graph for other queries, such as Why, ExportGraph and
+// Inventory. Changed files are given in Config.ChangedFiles or detected by a
+// git.ChangedFilesProvider. NewResultDocument turns a Result into the versioned JSON document
+// printed by the CLI and the GitHub Action.
+//
+// The package does not write to stdout or stderr. Diagnostics are returned with the result
+// and passed to Config.DiagnosticHandler as they are reported. Every operation that reads the
+// repository takes a context.Context and stops early when it is canceled.
+package tarm
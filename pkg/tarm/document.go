@@ -0,0 +1,120 @@
+package tarm
+
+import (
+	"encoding/json"
+	"runtime/debug"
+	"time"
+)
+
+// ResultSchemaVersion is the version of the ResultDocument format. It is incremented
+// whenever a field is removed or its meaning changes.
+const ResultSchemaVersion = 1
+
+// ResultSchemaURL is the location of the JSON Schema describing ResultDocument.
+const ResultSchemaURL = "https://raw.githubusercontent.com/kzmshx/tarm/main/schema/result.schema.json"
+
+// ResultDocument is the machine-readable output of an analysis run, shared by the CLI and
+// the GitHub Action. Slices are never nil, so they are encoded as empty arrays.
+type ResultDocument struct {
+	SchemaVersion         int                  `json:"schema_version"`
+	TarmVersion           string               `json:"tarm_version"`
+	Root                  string               `json:"root"`
+	PathBase              PathBase             `json:"path_base"`
+	RootModulePatterns    []string             `json:"root_module_patterns"`
+	ExcludeModulePatterns []string             `json:"exclude_module_patterns"`
+	Refs                  *Refs                `json:"refs,omitempty"`
+	ChangedFiles          []ChangedFile        `json:"changed_files"`
+	UnmappedFiles         []ChangedFile        `json:"unmapped_files"`
+	IgnoredFiles          []ChangedFile        `json:"ignored_files"`
+	AffectedModules       []AffectedRootModule `json:"affected_modules"`
+	Cycles                []Cycle              `json:"cycles"`
+	Diagnostics           []Diagnostic         `json:"diagnostics"`
+	Timings               Timings              `json:"timings"`
+}
+
+// NewResultDocument returns the document describing the result of running cfg.
+func NewResultDocument(cfg Config, r *Result) *ResultDocument {
+	root := cfg.Root
+	if root == "" {
+		root = "."
+	}
+	pathBase := cfg.PathBase
+	if pathBase == "" {
+		pathBase = PathBaseRoot
+	}
+	return &ResultDocument{
+		SchemaVersion:         ResultSchemaVersion,
+		TarmVersion:           Version(),
+		Root:                  root,
+		PathBase:              pathBase,
+		RootModulePatterns:    nonNil(cfg.RootModulePatterns),
+		ExcludeModulePatterns: nonNil(cfg.ExcludeModulePatterns),
+		Refs:                  r.Refs,
+		ChangedFiles:          nonNil(r.ChangedFiles),
+		UnmappedFiles:         nonNil(r.UnmappedFiles()),
+		IgnoredFiles:          nonNil(r.IgnoredFiles()),
+		AffectedModules:       nonNil(r.AffectedModules),
+		Cycles:                nonNil(r.Cycles),
+		Diagnostics:           nonNil(r.Diagnostics),
+		Timings:               r.Timings,
+	}
+}
+
+// MarshalJSON encodes the timings in milliseconds.
+func (t Timings) MarshalJSON() ([]byte, error) {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	return json.Marshal(struct {
+		ChangeDetection float64 `json:"change_detection_ms"`
+		Analysis        float64 `json:"analysis_ms"`
+		Total           float64 `json:"total_ms"`
+	}{ms(t.ChangeDetection), ms(t.Analysis), ms(t.Total)})
+}
+
+// Version returns the version of the tarm module the binary was built with, whether it is the
+// tarm CLI or another program using tarm as a library, or "(devel)" if it is unknown.
+func Version() string {
+	info, _ := debug.ReadBuildInfo()
+	return versionFrom(info)
+}
+
+func versionFrom(info *debug.BuildInfo) string {
+	if version := moduleVersion(info); version != "" {
+		return version
+	}
+	return "(devel)"
+}
+
+// modulePath is the path of the Go module tarm is built from.
+const modulePath = "github.com/kzmshx/tarm"
+
+// moduleVersion returns the version of the tarm module in the build, whether tarm is the main
+// module or a dependency of it, or an empty string if it is unknown.
+func moduleVersion(info *debug.BuildInfo) string {
+	if info == nil {
+		return ""
+	}
+	version := ""
+	if info.Main.Path == modulePath {
+		version = info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != modulePath {
+			continue
+		}
+		version = dep.Version
+		if dep.Replace != nil {
+			version = dep.Replace.Version
+		}
+	}
+	if version == "(devel)" {
+		return ""
+	}
+	return version
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,256 @@
+package tarm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRun_ChangedFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want []ChangedFile
+	}{
+		{
+			name: "modules, unmapped and outside paths",
+			cfg: Config{
+				Root:               filepath.Join("..", "..", "testdata", "terraform"),
+				RootModulePatterns: []string{"environments/*/*"},
+				ChangedFiles:       []string{"modules/database/main.tf", "README.md", "../outside.tf"},
+			},
+			want: []ChangedFile{
+				{Path: "../outside.tf", Status: ChangeOutsideRoot},
+				{Path: "README.md", Status: ChangeNoModule},
+				{Path: "modules/database/main.tf", Status: ChangeMapped, Sources: []Cause{{Path: "modules/database", Reason: ReasonModule}}},
+			},
+		},
+		{
+			name: "referenced files",
+			cfg: Config{
+				Root:               filepath.Join("..", "..", "testdata", "terraform-assets"),
+				RootModulePatterns: []string{"environments/*"},
+				ChangedFiles:       []string{"environments/prod/templates/user_data.tpl"},
+			},
+			want: []ChangedFile{
+				{Path: "environments/prod/templates/user_data.tpl", Status: ChangeMapped, Sources: []Cause{{Path: "environments/prod/templates/user_data.tpl", Reason: ReasonFileReference}}},
+			},
+		},
+		{
+			name: "var files",
+			cfg: Config{
+				Root:               filepath.Join("..", "..", "testdata", "terraform-targets"),
+				RootModulePatterns: []string{"services/*"},
+				ChangedFiles:       []string{"services/api/envs/prod.tfvars"},
+				Targets:            []TargetRule{{RootModulePattern: "services/api", VarFiles: []string{"envs/*.tfvars"}}},
+			},
+			want: []ChangedFile{
+				{Path: "services/api/envs/prod.tfvars", Status: ChangeMapped, Sources: []Cause{{Path: "services/api", Reason: ReasonVarFile}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Run(t.Context(), tt.cfg, nil)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.ChangedFiles, tt.want) {
+				t.Errorf("ChangedFiles = %+v, want %+v", result.ChangedFiles, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewResultDocument(t *testing.T) {
+	cfg := Config{Root: filepath.Join("..", "..", "testdata", "terraform"), RootModulePatterns: []string{"environments/*/*"}}
+	result, err := Run(t.Context(), cfg, nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	data, err := json.Marshal(NewResultDocument(cfg, result))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["schema_version"] != float64(ResultSchemaVersion) {
+		t.Errorf("schema_version = %v, want %d", got["schema_version"], ResultSchemaVersion)
+	}
+	for _, key := range []string{"exclude_module_patterns", "changed_files", "affected_modules", "cycles", "diagnostics"} {
+		if v, ok := got[key].([]any); !ok || len(v) != 0 {
+			t.Errorf("%s = %v, want an empty array", key, got[key])
+		}
+	}
+	if _, ok := got["refs"]; ok {
+		t.Error("refs should be omitted without change detection")
+	}
+}
+
+func TestVersionFrom(t *testing.T) {
+	tests := []struct {
+		name string
+		info *debug.BuildInfo
+		want string
+	}{
+		{name: "tarm binary", info: &debug.BuildInfo{Main: debug.Module{Path: modulePath, Version: "v1.2.0"}}, want: "v1.2.0"},
+		{
+			name: "library in another program",
+			info: &debug.BuildInfo{
+				Main: debug.Module{Path: "example.com/ci", Version: "v9.0.0"},
+				Deps: []*debug.Module{{Path: modulePath, Version: "v1.2.0"}},
+			},
+			want: "v1.2.0",
+		},
+		{name: "go run", info: &debug.BuildInfo{Main: debug.Module{Path: modulePath, Version: "(devel)"}}, want: "(devel)"},
+		{name: "program without tarm", info: &debug.BuildInfo{Main: debug.Module{Path: "example.com/ci", Version: "v9.0.0"}}, want: "(devel)"},
+		{name: "no build info", info: nil, want: "(devel)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := versionFrom(tt.info); got != tt.want {
+				t.Errorf("versionFrom() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResultSchema(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "schema", "result.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema["$id"] != ResultSchemaURL {
+		t.Errorf("$id = %v, want %s", schema["$id"], ResultSchemaURL)
+	}
+	if version := schema["properties"].(map[string]any)["schema_version"].(map[string]any)["const"]; version != float64(ResultSchemaVersion) {
+		t.Errorf("schema_version const = %v, want %d", version, ResultSchemaVersion)
+	}
+	codes := schema["$defs"].(map[string]any)["diagnostic"].(map[string]any)["properties"].(map[string]any)["code"].(map[string]any)["enum"].([]any)
+	for _, code := range DiagnosticCodes {
+		if !slices.Contains(codes, any(string(code))) {
+			t.Errorf("diagnostic code %q is missing from the schema", code)
+		}
+	}
+
+	// Documents covering every optional field must validate against the schema.
+	testdata := filepath.Join("..", "..", "testdata")
+	configs := []Config{
+		{
+			Root:               filepath.Join(testdata, "terraform"),
+			RootModulePatterns: []string{"environments/*/*"},
+			ChangedFiles:       []string{"modules/common/main.tf", "README.md", "../outside.tf"},
+		},
+		{
+			Root:               filepath.Join(testdata, "terraform-targets"),
+			RootModulePatterns: []string{"services/*"},
+			ChangedFiles:       []string{"modules/app/main.tf", "services/api/envs/prod.tfvars"},
+			Targets:            []TargetRule{{RootModulePattern: "services/*", VarFiles: []string{"envs/*.tfvars"}, Workspaces: []string{"dev"}}},
+		},
+		{
+			Root:               filepath.Join(testdata, "terraform-errors"),
+			RootModulePatterns: []string{"*"},
+			ChangedFiles:       []string{"circular/module-a/main.tf"},
+		},
+	}
+	for _, cfg := range configs {
+		t.Run(filepath.Base(cfg.Root), func(t *testing.T) {
+			result, err := Run(t.Context(), cfg, nil)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			result.Refs = &Refs{Base: "origin/main", Head: "HEAD", BaseSHA: "a", HeadSHA: "b"}
+			data, err := json.Marshal(NewResultDocument(cfg, result))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc any
+			if err := json.Unmarshal(data, &doc); err != nil {
+				t.Fatal(err)
+			}
+			if err := validateSchema(schema, schema, doc, "$"); err != nil {
+				t.Errorf("document does not match the schema: %v\n%s", err, data)
+			}
+		})
+	}
+}
+
+// validateSchema validates v against the subset of JSON Schema used by result.schema.json.
+func validateSchema(root, schema map[string]any, v any, at string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/$defs/")
+		return validateSchema(root, root["$defs"].(map[string]any)[name].(map[string]any), v, at)
+	}
+	if c, ok := schema["const"]; ok && c != v {
+		return fmt.Errorf("%s: %v is not %v", at, v, c)
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, v) {
+		return fmt.Errorf("%s: %v is not one of %v", at, v, enum)
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: %v is not an object", at, v)
+		}
+		props, _ := schema["properties"].(map[string]any)
+		required, _ := schema["required"].([]any)
+		for _, key := range required {
+			if _, ok := obj[key.(string)]; !ok {
+				return fmt.Errorf("%s: missing required property %s", at, key)
+			}
+		}
+		for key, value := range obj {
+			prop, ok := props[key].(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s: unexpected property %s", at, key)
+			}
+			if err := validateSchema(root, prop, value, at+"."+key); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: %v is not an array", at, v)
+		}
+		for i, item := range arr {
+			if err := validateSchema(root, schema["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string", "boolean", "number", "integer":
+		var ok bool
+		switch schema["type"] {
+		case "string":
+			_, ok = v.(string)
+		case "boolean":
+			_, ok = v.(bool)
+		case "number":
+			_, ok = v.(float64)
+		case "integer":
+			f, isNumber := v.(float64)
+			ok = isNumber && f == float64(int64(f))
+		}
+		if !ok {
+			return fmt.Errorf("%s: %v is not of type %s", at, v, schema["type"])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package tarm_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/kzmshx/tarm/pkg/git"
+	"github.com/kzmshx/tarm/pkg/tarm"
+)
+
+func ExampleRun() {
+	cfg := tarm.Config{
+		Root:               filepath.Join("..", "..", "testdata", "terraform"),
+		RootModulePatterns: []string{"environments/*/*"},
+		DetectChanges:      true,
+	}
+	provider := &git.StaticProvider{Files: []string{"modules/database/main.tf"}}
+
+	result, err := tarm.Run(context.Background(), cfg, provider)
+	if err != nil {
+		panic(err)
+	}
+	for _, m := range result.AffectedModules {
+		fmt.Println(m.Path, m.AffectedBy)
+	}
+	// Output:
+	// environments/dev/api [modules/database]
+	// environments/stg/api [modules/database]
+}
+
+func ExampleAnalyzer() {
+	a := tarm.NewAnalyzer(filepath.Join("..", "..", "testdata", "terraform"))
+	if err := a.Analyze(context.Background()); err != nil {
+		panic(err)
+	}
+	fmt.Println(a.GetDependencyGraph().Dependents("modules/database"))
+	// Output:
+	// [environments/dev/api environments/stg/api]
+}
@@ -0,0 +1,218 @@
+package tarm
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/kzmshx/tarm/pkg/git"
+)
+
+// GraphDocumentVersion is the version of the GraphDocument format. It is incremented
+// whenever a field is removed or its meaning changes.
+const GraphDocumentVersion = 1
+
+// NodeKind classifies a node of an exported graph.
+type NodeKind string
+
+const (
+	// NodeRoot is a root module.
+	NodeRoot NodeKind = "root"
+	// NodeModule is a module that is not a root module.
+	NodeModule NodeKind = "module"
+	// NodeFile is a file or directory read by a module.
+	NodeFile NodeKind = "file"
+	// NodeExternal is a module source fetched from outside the repository.
+	NodeExternal NodeKind = "external"
+)
+
+// GraphDocument is an exported dependency graph.
+type GraphDocument struct {
+	Version int         `json:"version"`
+	Nodes   []GraphNode `json:"nodes"`
+	Edges   []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a node of an exported graph. Module and file IDs are relative to the
+// root directory; external IDs are source addresses.
+type GraphNode struct {
+	ID   string   `json:"id"`
+	Kind NodeKind `json:"kind"`
+	// Changed marks modules and files that changed paths map to.
+	Changed bool `json:"changed,omitempty"`
+	// Affected marks nodes that depend on a changed node, including the changed nodes themselves.
+	Affected bool          `json:"affected,omitempty"`
+	Source   *ModuleSource `json:"source,omitempty"`
+}
+
+// GraphEdge is an edge of an exported graph where From depends on To.
+type GraphEdge struct {
+	From string   `json:"from"`
+	To   string   `json:"to"`
+	Kind EdgeKind `json:"kind"`
+}
+
+// ExportGraph analyzes the root directory and returns its dependency graph, including remote
+// module sources. Nodes are marked as changed or affected by the changed paths in the config and,
+// when change detection is enabled, those reported by the provider. If focus is not empty, only
+// the given modules and the nodes they depend on or that depend on them are kept.
+func ExportGraph(ctx context.Context, cfg Config, changeProvider git.ChangedFilesProvider, focus []string) (*GraphDocument, error) {
+	root := cfg.Root
+	if root == "" {
+		root = "."
+	}
+	cfg, repo, err := rebaseConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if repo != nil {
+		rebased := make([]string, 0, len(focus))
+		for _, module := range focus {
+			if !filepath.IsAbs(module) {
+				module = repo.path(module)
+			}
+			rebased = append(rebased, module)
+		}
+		focus = rebased
+	}
+
+	changedFiles, _, analyzerOpts, err := collectChanges(ctx, cfg, changeProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	a := NewAnalyzer(root, analyzerOpts...)
+	if err := a.Analyze(ctx); err != nil {
+		return nil, fmt.Errorf("failed to analyze modules: %w", err)
+	}
+	matchRootModule, err := rootModuleMatcher(cfg, a)
+	if err != nil {
+		return nil, err
+	}
+
+	g := a.GetDependencyGraph()
+	nodes := make(map[string]*GraphNode)
+	addNode := func(id string, kind NodeKind) *GraphNode {
+		if n, ok := nodes[id]; ok {
+			return n
+		}
+		if kind == NodeModule && matchRootModule(id) {
+			kind = NodeRoot
+		}
+		nodes[id] = &GraphNode{ID: id, Kind: kind}
+		return nodes[id]
+	}
+
+	for _, module := range a.modules {
+		addNode(module, NodeModule)
+	}
+	edges := make(map[[2]string]EdgeKind)
+	for _, from := range g.GetAllModules() {
+		for _, to := range g.Dependencies(from) {
+			kind := g.Kind(from, to)
+			if kind == EdgeFileReference {
+				addNode(to, NodeFile)
+			} else {
+				addNode(to, NodeModule)
+			}
+			edges[[2]string{from, to}] = kind
+		}
+	}
+	for _, call := range g.ModuleCalls() {
+		if call.Local != "" || !call.Source.IsRemote() {
+			continue
+		}
+		n := addNode(call.Source.Address, NodeExternal)
+		if n.Source == nil {
+			source := call.Source
+			n.Source = &source
+		}
+		edges[[2]string{call.Module, call.Source.Address}] = EdgeModuleCall
+	}
+
+	// Mark the nodes changed paths map to and everything that depends on them.
+	var changed []string
+	for _, changePath := range changedFiles {
+		sources, _ := a.mapChange(changePath)
+		for _, source := range sources {
+			if source.reason == ReasonInherited || source.reason == ReasonGlobalTrigger {
+				for _, module := range a.modules {
+					if matchRootModule(module) && a.reachesDirectly(source, module) {
+						nodes[module].Affected = true
+					}
+				}
+				continue
+			}
+			kind := NodeModule
+			if source.reason == ReasonFileReference {
+				kind = NodeFile
+			}
+			addNode(source.path, kind).Changed = true
+			changed = append(changed, source.path)
+		}
+	}
+	for module := range g.AffectedModulesBySource(changed) {
+		addNode(module, NodeModule).Affected = true
+	}
+
+	keep := func(string) bool { return true }
+	if len(focus) > 0 {
+		kept := make(map[string]bool)
+		for _, module := range focus {
+			module = filepath.Clean(module)
+			if _, ok := nodes[module]; !ok {
+				return nil, fmt.Errorf("module %q not found", module)
+			}
+			for _, n := range g.GetAffectedModules(module) {
+				kept[n] = true
+			}
+			for _, n := range dependenciesOf(module, edges) {
+				kept[n] = true
+			}
+		}
+		keep = func(id string) bool { return kept[id] }
+	}
+
+	doc := &GraphDocument{Version: GraphDocumentVersion, Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	for id, n := range nodes {
+		if keep(id) {
+			doc.Nodes = append(doc.Nodes, *n)
+		}
+	}
+	for edge, kind := range edges {
+		if keep(edge[0]) && keep(edge[1]) {
+			doc.Edges = append(doc.Edges, GraphEdge{From: edge[0], To: edge[1], Kind: kind})
+		}
+	}
+	sort.Slice(doc.Nodes, func(i, j int) bool {
+		return doc.Nodes[i].ID < doc.Nodes[j].ID
+	})
+	sort.Slice(doc.Edges, func(i, j int) bool {
+		if doc.Edges[i].From != doc.Edges[j].From {
+			return doc.Edges[i].From < doc.Edges[j].From
+		}
+		return doc.Edges[i].To < doc.Edges[j].To
+	})
+	return doc, nil
+}
+
+// dependenciesOf returns the node and every node it depends on, transitively.
+func dependenciesOf(node string, edges map[[2]string]EdgeKind) []string {
+	deps := make(map[string][]string)
+	for edge := range edges {
+		deps[edge[0]] = append(deps[edge[0]], edge[1])
+	}
+
+	visited := map[string]bool{node: true}
+	result := []string{node}
+	for i := 0; i < len(result); i++ {
+		for _, dep := range deps[result[i]] {
+			if !visited[dep] {
+				visited[dep] = true
+				result = append(result, dep)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+package tarm
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExportGraph(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform")
+
+	tests := []struct {
+		name         string
+		changedFiles []string
+		focus        []string
+		wantNodes    []string
+		wantEdges    []string
+		wantErr      bool
+	}{
+		{
+			name:      "focus keeps dependencies and dependents",
+			focus:     []string{"modules/auth"},
+			wantNodes: []string{"environments/dev/web:root", "environments/stg/api:root", "environments/stg/web:root", "modules/auth:module", "modules/common:module"},
+			wantEdges: []string{"environments/dev/web->modules/auth", "environments/stg/api->modules/auth", "environments/stg/web->modules/auth", "modules/auth->modules/common"},
+		},
+		{
+			name:         "changed and affected nodes are marked",
+			changedFiles: []string{"modules/database/main.tf"},
+			focus:        []string{"environments/dev/api"},
+			wantNodes:    []string{"environments/dev/api:root:affected", "modules/common:module", "modules/database:module:changed:affected", "modules/network:module"},
+			wantEdges:    []string{"environments/dev/api->modules/database", "environments/dev/api->modules/network", "modules/database->modules/common"},
+		},
+		{
+			name:      "remote sources are external nodes",
+			focus:     []string{"environments/standalone/simple"},
+			wantNodes: []string{"environments/standalone/simple:root", "terraform-aws-modules/vpc/aws:external"},
+			wantEdges: []string{"environments/standalone/simple->terraform-aws-modules/vpc/aws"},
+		},
+		{
+			name:    "unknown focus returns error",
+			focus:   []string{"modules/unknown"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Root: testRoot, RootModulePatterns: []string{"environments/*/*"}, ChangedFiles: tt.changedFiles}
+			doc, err := ExportGraph(t.Context(), cfg, nil, tt.focus)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExportGraph() error = %v", err)
+			}
+
+			if doc.Version != GraphDocumentVersion {
+				t.Errorf("version = %d, want %d", doc.Version, GraphDocumentVersion)
+			}
+			var gotNodes, gotEdges []string
+			for _, n := range doc.Nodes {
+				s := n.ID + ":" + string(n.Kind)
+				if n.Changed {
+					s += ":changed"
+				}
+				if n.Affected {
+					s += ":affected"
+				}
+				gotNodes = append(gotNodes, s)
+			}
+			for _, e := range doc.Edges {
+				gotEdges = append(gotEdges, e.From+"->"+e.To)
+			}
+			if !reflect.DeepEqual(gotNodes, tt.wantNodes) {
+				t.Errorf("nodes = %v, want %v", gotNodes, tt.wantNodes)
+			}
+			if !reflect.DeepEqual(gotEdges, tt.wantEdges) {
+				t.Errorf("edges = %v, want %v", gotEdges, tt.wantEdges)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package tarm
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+)
+
+var (
+	fallbackModulePattern   = regexp.MustCompile(`^\s*module\s+"([^"]+)"\s*\{`)
+	fallbackSourcePattern   = regexp.MustCompile(`^\s*source\s*=\s*"([^"]*)"`)
+	fallbackVersionPattern  = regexp.MustCompile(`^\s*version\s*=\s*"([^"]*)"`)
+	fallbackTopLevelPattern = regexp.MustCompile(`^(module|resource|data|variable|output|locals|provider|terraform|moved|import|removed|check)\b`)
+)
+
+// scanModuleCalls extracts module calls from the native syntax configuration files in dir
+// line by line, without parsing them. It is a fallback for modules with syntax errors, so a
+// broken file still contributes the module calls it declares. A module block ends at the next
+// top-level block, which tolerates missing closing braces.
+func scanModuleCalls(dir string, tofu bool) (map[string]*tfconfig.ModuleCall, error) {
+	files, err := configFiles(dir, tofu)
+	if err != nil {
+		return nil, err
+	}
+
+	calls := make(map[string]*tfconfig.ModuleCall)
+	for _, filename := range files {
+		if ext := configExt(filename); ext != extTerraform && ext != extTofu {
+			continue
+		}
+
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		var current *tfconfig.ModuleCall
+		scanner := bufio.NewScanner(f)
+		for line := 1; scanner.Scan(); line++ {
+			text := scanner.Text()
+			if m := fallbackModulePattern.FindStringSubmatch(text); m != nil {
+				current = nil
+				if _, exists := calls[m[1]]; !exists {
+					current = &tfconfig.ModuleCall{Name: m[1], Pos: tfconfig.SourcePos{Filename: filename, Line: line}}
+					calls[m[1]] = current
+				}
+				continue
+			}
+			if fallbackTopLevelPattern.MatchString(text) {
+				current = nil
+				continue
+			}
+			if current == nil {
+				continue
+			}
+			if m := fallbackSourcePattern.FindStringSubmatch(text); m != nil && current.Source == "" {
+				current.Source = m[1]
+			} else if m := fallbackVersionPattern.FindStringSubmatch(text); m != nil && current.Version == "" {
+				current.Version = m[1]
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for name, call := range calls {
+		if call.Source == "" || strings.Contains(call.Source, "${") {
+			delete(calls, name)
+		}
+	}
+	return calls, nil
+}
@@ -0,0 +1,39 @@
+package tarm
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestScanModuleCalls(t *testing.T) {
+	dir, _ := filepath.Abs(filepath.Join("..", "..", "testdata", "terraform-parse-errors", "environments", "broken"))
+
+	calls, err := scanModuleCalls(dir, false)
+	if err != nil {
+		t.Fatalf("scanModuleCalls() error = %v", err)
+	}
+
+	want := map[string]struct {
+		source string
+		line   int
+	}{
+		"network": {"../../modules/network", 1},
+		"storage": {"../../modules/storage", 10},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("got %d calls, want %d", len(calls), len(want))
+	}
+	for name, w := range want {
+		call, ok := calls[name]
+		if !ok {
+			t.Errorf("missing module call %q", name)
+			continue
+		}
+		if call.Source != w.source || call.Pos.Line != w.line {
+			t.Errorf("%s: got source %q line %d, want %q line %d", name, call.Source, call.Pos.Line, w.source, w.line)
+		}
+		if call.Pos.Filename != filepath.Join(dir, "main.tf") {
+			t.Errorf("%s: filename = %s", name, call.Pos.Filename)
+		}
+	}
+}
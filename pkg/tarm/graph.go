@@ -0,0 +1,409 @@
+package tarm
+
+import (
+	"fmt"
+	mathbits "math/bits"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// EdgeKind classifies a dependency edge.
+type EdgeKind string
+
+const (
+	// EdgeModuleCall is an edge from a module to a local module it calls.
+	EdgeModuleCall EdgeKind = "module"
+	// EdgeFileReference is an edge from a module to a file or directory it reads,
+	// e.g. via file(), templatefile() or an archive_file data source.
+	EdgeFileReference EdgeKind = "file"
+	// EdgeRemoteState is an edge from a module reading a state through a terraform_remote_state
+	// data source to the module whose backend stores that state. Both are normally root modules.
+	EdgeRemoteState EdgeKind = "remote_state"
+	// EdgeDependency is an edge from a Terragrunt unit to a unit listed in its
+	// dependency or dependencies blocks.
+	EdgeDependency EdgeKind = "dependency"
+)
+
+// DependencyGraph represents the dependency relationships between Terraform modules.
+// Modules are interned to integer IDs; edges are kept in a set keyed by their endpoints,
+// with forward and reverse adjacency lists for traversal.
+type DependencyGraph struct {
+	ids   map[string]int
+	names []string
+	// out and in are the forward (module -> modules it depends on) and reverse
+	// (module -> modules that depend on it) adjacency lists. inKinds holds the kinds
+	// of the edges in in.
+	out     [][]int
+	in      [][]int
+	inKinds [][]EdgeKind
+	// edges holds the kind of every edge, keyed by edgeKey.
+	edges map[uint64]EdgeKind
+
+	calls []ModuleCall
+	// callIndex maps the endpoints of module call edges to the first call creating them.
+	callIndex map[[2]int]ModuleCall
+
+	// closure memoizes GetAffectedModulesKind when enabled.
+	closure map[string][]string
+}
+
+// ModuleCall is a module block, or the terraform block of a Terragrunt unit, with its parsed source.
+type ModuleCall struct {
+	// Module is the calling module directory, relative to the root directory.
+	Module string `json:"module"`
+	// Name is the module block label, or "terraform" for a Terragrunt unit.
+	Name    string       `json:"name"`
+	Source  ModuleSource `json:"source"`
+	Version string       `json:"version,omitempty"`
+	// Local is the module directory the call resolves to, relative to the root directory,
+	// either as a local path or through a source rewrite rule.
+	Local string `json:"local,omitempty"`
+	// File and Line locate the block. File is relative to the root directory.
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+// NewDependencyGraph creates a new dependency graph.
+func NewDependencyGraph() *DependencyGraph {
+	return &DependencyGraph{
+		ids:       make(map[string]int),
+		edges:     make(map[uint64]EdgeKind),
+		callIndex: make(map[[2]int]ModuleCall),
+	}
+}
+
+// EnableClosureMemo makes the graph memoize the transitive dependents computed by
+// GetAffectedModulesKind until the next edge is added. It speeds up repeated queries
+// for the same modules at the cost of memory.
+func (g *DependencyGraph) EnableClosureMemo() {
+	g.closure = make(map[string][]string)
+}
+
+// node returns the ID of a module, adding it to the graph if needed.
+func (g *DependencyGraph) node(name string) int {
+	if id, ok := g.ids[name]; ok {
+		return id
+	}
+	id := len(g.names)
+	g.ids[name] = id
+	g.names = append(g.names, name)
+	g.out = append(g.out, nil)
+	g.in = append(g.in, nil)
+	g.inKinds = append(g.inKinds, nil)
+	return id
+}
+
+func edgeKey(from, to int) uint64 {
+	return uint64(from)<<32 | uint64(uint32(to))
+}
+
+// AddDependency adds a module call dependency where 'from' depends on 'to'.
+func (g *DependencyGraph) AddDependency(from, to string) {
+	g.AddDependencyKind(from, to, EdgeModuleCall)
+}
+
+// AddDependencyKind adds a dependency relationship of the given kind where 'from' depends on 'to'.
+// If the edge already exists, its kind is left unchanged.
+func (g *DependencyGraph) AddDependencyKind(from, to string, kind EdgeKind) {
+	f := g.node(filepath.Clean(from))
+	t := g.node(filepath.Clean(to))
+
+	key := edgeKey(f, t)
+	if _, ok := g.edges[key]; ok {
+		return
+	}
+	g.edges[key] = kind
+	g.out[f] = append(g.out[f], t)
+	g.in[t] = append(g.in[t], f)
+	g.inKinds[t] = append(g.inKinds[t], kind)
+	if g.closure != nil {
+		g.closure = make(map[string][]string)
+	}
+}
+
+// Kind returns the kind of the edge from 'from' to 'to', or an empty string if there is no such edge.
+func (g *DependencyGraph) Kind(from, to string) EdgeKind {
+	f, ok := g.ids[filepath.Clean(from)]
+	if !ok {
+		return ""
+	}
+	t, ok := g.ids[filepath.Clean(to)]
+	if !ok {
+		return ""
+	}
+	return g.edges[edgeKey(f, t)]
+}
+
+// Dependencies returns the modules the given module depends on, sorted.
+func (g *DependencyGraph) Dependencies(module string) []string {
+	id, ok := g.ids[filepath.Clean(module)]
+	if !ok {
+		return nil
+	}
+	return g.sortedNames(g.out[id])
+}
+
+// Dependents returns the modules that depend on the given module, sorted.
+func (g *DependencyGraph) Dependents(module string) []string {
+	id, ok := g.ids[filepath.Clean(module)]
+	if !ok {
+		return nil
+	}
+	return g.sortedNames(g.in[id])
+}
+
+func (g *DependencyGraph) sortedNames(ids []int) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = g.names[id]
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FileReferences returns all files and directories referenced by modules, sorted.
+func (g *DependencyGraph) FileReferences() []string {
+	seen := make(map[int]bool)
+	var result []string
+	for key, kind := range g.edges {
+		if to := int(uint32(key)); kind == EdgeFileReference && !seen[to] {
+			seen[to] = true
+			result = append(result, g.names[to])
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// GetAffectedModules returns all modules that depend on the given path (transitively).
+func (g *DependencyGraph) GetAffectedModules(path string) []string {
+	return g.GetAffectedModulesKind(path)
+}
+
+// GetAffectedModulesKind returns all modules that depend on the given path (transitively),
+// including the path itself, following only edges of the given kinds. If no kinds are
+// given, all edges are followed.
+func (g *DependencyGraph) GetAffectedModulesKind(path string, kinds ...EdgeKind) []string {
+	path = filepath.Clean(path)
+
+	var memoKey string
+	if g.closure != nil {
+		memoKey = fmt.Sprint(path, kinds)
+		if result, ok := g.closure[memoKey]; ok {
+			return slices.Clone(result)
+		}
+	}
+
+	result := []string{path}
+	if id, ok := g.ids[path]; ok {
+		result = result[:0]
+		for _, reached := range g.reverseReach([]int{id}, kinds) {
+			result = append(result, g.names[reached])
+		}
+	}
+
+	if g.closure != nil {
+		g.closure[memoKey] = slices.Clone(result)
+	}
+	return result
+}
+
+// AffectedModulesBySource returns every module that depends, transitively, on any of the
+// given paths, mapped to the paths it depends on in the order they were given. Paths map to
+// themselves. Only edges of the given kinds are followed; if no kinds are given, all edges are.
+// All paths are handled in a single traversal of the graph.
+func (g *DependencyGraph) AffectedModulesBySource(paths []string, kinds ...EdgeKind) map[string][]string {
+	paths = Unique(paths)
+	result := make(map[string][]string)
+
+	seedPaths := make(map[int][]int)
+	var seeds []int
+	for i, p := range paths {
+		clean := filepath.Clean(p)
+		id, ok := g.ids[clean]
+		if !ok {
+			result[clean] = append(result[clean], p)
+			continue
+		}
+		if _, ok := seedPaths[id]; !ok {
+			seeds = append(seeds, id)
+		}
+		seedPaths[id] = append(seedPaths[id], i)
+	}
+	nodes := g.reverseReach(seeds, kinds)
+
+	// Every reachable node gets a bitset of the indices of the paths it depends on,
+	// all allocated at once. pos maps node IDs to their position in nodes.
+	words := (len(paths) + 63) / 64
+	bitsets := make([]uint64, len(nodes)*words)
+	pos := make([]int, len(g.names))
+	for i, id := range nodes {
+		pos[id] = i
+	}
+	bitset := func(id int) []uint64 {
+		return bitsets[pos[id]*words : (pos[id]+1)*words]
+	}
+	for id, indices := range seedPaths {
+		bits := bitset(id)
+		for _, i := range indices {
+			bits[i/64] |= 1 << (i % 64)
+		}
+	}
+	merge := func(dependent, current int) bool {
+		changed := false
+		target := bitset(dependent)
+		for w, bits := range bitset(current) {
+			if merged := target[w] | bits; merged != target[w] {
+				target[w] = merged
+				changed = true
+			}
+		}
+		return changed
+	}
+
+	// Visit the reachable nodes in topological order, so that every node is final before its
+	// bits are passed on. pending counts the reachable dependencies not yet visited.
+	pending := make([]int, len(g.names))
+	for _, current := range nodes {
+		for j, dependent := range g.in[current] {
+			if follows(g.inKinds[current][j], kinds) {
+				pending[dependent]++
+			}
+		}
+	}
+	var queue []int
+	for _, id := range nodes {
+		if pending[id] == 0 {
+			queue = append(queue, id)
+		}
+	}
+	for i := 0; i < len(queue); i++ {
+		current := queue[i]
+		for j, dependent := range g.in[current] {
+			if !follows(g.inKinds[current][j], kinds) {
+				continue
+			}
+			merge(dependent, current)
+			if pending[dependent]--; pending[dependent] == 0 {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	// Nodes on cycles, and the nodes depending on them, are left over. Propagate among them
+	// until nothing changes.
+	if len(queue) < len(nodes) {
+		var cyclic []int
+		inQueue := make([]bool, len(g.names))
+		for _, id := range nodes {
+			if pending[id] > 0 {
+				cyclic = append(cyclic, id)
+				inQueue[id] = true
+			}
+		}
+		for len(cyclic) > 0 {
+			current := cyclic[0]
+			cyclic = cyclic[1:]
+			inQueue[current] = false
+			for j, dependent := range g.in[current] {
+				if follows(g.inKinds[current][j], kinds) && merge(dependent, current) && !inQueue[dependent] {
+					inQueue[dependent] = true
+					cyclic = append(cyclic, dependent)
+				}
+			}
+		}
+	}
+
+	for _, id := range nodes {
+		var reachedBy []string
+		for w, bits := range bitset(id) {
+			for bits != 0 {
+				reachedBy = append(reachedBy, paths[w*64+mathbits.TrailingZeros64(bits)])
+				bits &= bits - 1
+			}
+		}
+		result[g.names[id]] = reachedBy
+	}
+	return result
+}
+
+// reverseReach returns the given nodes and every node depending on them, transitively,
+// in breadth-first order, following only edges of the given kinds.
+func (g *DependencyGraph) reverseReach(start []int, kinds []EdgeKind) []int {
+	visited := make([]bool, len(g.names))
+	var result []int
+	for _, id := range start {
+		if !visited[id] {
+			visited[id] = true
+			result = append(result, id)
+		}
+	}
+	for i := 0; i < len(result); i++ {
+		current := result[i]
+		for j, dependent := range g.in[current] {
+			if !visited[dependent] && follows(g.inKinds[current][j], kinds) {
+				visited[dependent] = true
+				result = append(result, dependent)
+			}
+		}
+	}
+	return result
+}
+
+// follows reports whether an edge of the given kind is followed when only the given kinds are.
+// If no kinds are given, all edges are followed.
+func follows(kind EdgeKind, kinds []EdgeKind) bool {
+	return len(kinds) == 0 || slices.Contains(kinds, kind)
+}
+
+// AddModuleCall records a module call found in a module.
+func (g *DependencyGraph) AddModuleCall(call ModuleCall) {
+	g.calls = append(g.calls, call)
+	if call.Local == "" {
+		return
+	}
+	key := [2]int{g.node(filepath.Clean(call.Module)), g.node(filepath.Clean(call.Local))}
+	if found, ok := g.callIndex[key]; !ok || call.File < found.File || call.File == found.File && call.Line < found.Line {
+		g.callIndex[key] = call
+	}
+}
+
+// ModuleCalls returns every recorded module call, sorted by module, file and line.
+func (g *DependencyGraph) ModuleCalls() []ModuleCall {
+	calls := slices.Clone(g.calls)
+	sort.SliceStable(calls, func(i, j int) bool {
+		a, b := calls[i], calls[j]
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return calls
+}
+
+// GetAllModules returns all modules in the graph.
+func (g *DependencyGraph) GetAllModules() []string {
+	return slices.Clone(g.names)
+}
+
+// String returns a string representation of the graph, sorted by module.
+func (g *DependencyGraph) String() string {
+	var sb strings.Builder
+	sb.WriteString("Dependency Graph:\n")
+	modules := slices.Clone(g.names)
+	sort.Strings(modules)
+	for _, module := range modules {
+		if deps := g.Dependencies(module); len(deps) > 0 {
+			sb.WriteString(fmt.Sprintf("  %s -> %s\n", module, strings.Join(deps, ", ")))
+		}
+	}
+	return sb.String()
+}
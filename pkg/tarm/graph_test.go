@@ -0,0 +1,398 @@
+package tarm
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestAddDependency(t *testing.T) {
+	tests := []struct {
+		name     string
+		edges    [][2]string
+		wantDeps map[string][]string
+		wantRevs map[string][]string
+	}{
+		{
+			name:  "single dependency",
+			edges: [][2]string{{"a", "b"}},
+			wantDeps: map[string][]string{"a": {"b"}},
+			wantRevs: map[string][]string{"b": {"a"}},
+		},
+		{
+			name:  "multiple dependencies from one module",
+			edges: [][2]string{{"a", "b"}, {"a", "c"}},
+			wantDeps: map[string][]string{"a": {"b", "c"}},
+			wantRevs: map[string][]string{"b": {"a"}, "c": {"a"}},
+		},
+		{
+			name:  "duplicate dependency is not added twice",
+			edges: [][2]string{{"a", "b"}, {"a", "b"}},
+			wantDeps: map[string][]string{"a": {"b"}},
+			wantRevs: map[string][]string{"b": {"a"}},
+		},
+		{
+			name:  "path normalization cleans trailing slash",
+			edges: [][2]string{{"a/b/", "c/d/"}},
+			wantDeps: map[string][]string{"a/b": {"c/d"}},
+			wantRevs: map[string][]string{"c/d": {"a/b"}},
+		},
+		{
+			name:  "chain dependency a -> b -> c",
+			edges: [][2]string{{"a", "b"}, {"b", "c"}},
+			wantDeps: map[string][]string{"a": {"b"}, "b": {"c"}},
+			wantRevs: map[string][]string{"b": {"a"}, "c": {"b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewDependencyGraph()
+			for _, e := range tt.edges {
+				g.AddDependency(e[0], e[1])
+			}
+
+			for mod, wantDeps := range tt.wantDeps {
+				gotDeps := g.Dependencies(mod)
+				if len(gotDeps) != len(wantDeps) {
+					t.Errorf("Dependencies[%s]: got %v, want %v", mod, gotDeps, wantDeps)
+					continue
+				}
+				sort.Strings(gotDeps)
+				sort.Strings(wantDeps)
+				for i := range gotDeps {
+					if gotDeps[i] != wantDeps[i] {
+						t.Errorf("Dependencies[%s]: got %v, want %v", mod, gotDeps, wantDeps)
+						break
+					}
+				}
+			}
+
+			for mod, wantRevs := range tt.wantRevs {
+				gotRevs := g.Dependents(mod)
+				if len(gotRevs) != len(wantRevs) {
+					t.Errorf("Dependents[%s]: got %v, want %v", mod, gotRevs, wantRevs)
+					continue
+				}
+				sort.Strings(gotRevs)
+				sort.Strings(wantRevs)
+				for i := range gotRevs {
+					if gotRevs[i] != wantRevs[i] {
+						t.Errorf("Dependents[%s]: got %v, want %v", mod, gotRevs, wantRevs)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGetAffectedModules(t *testing.T) {
+	tests := []struct {
+		name        string
+		edges       [][2]string
+		changedPath string
+		wantModules []string
+	}{
+		{name: "direct dependent", edges: [][2]string{{"a", "b"}}, changedPath: "b", wantModules: []string{"a", "b"}},
+		{name: "transitive dependents", edges: [][2]string{{"a", "b"}, {"b", "c"}}, changedPath: "c", wantModules: []string{"a", "b", "c"}},
+		{name: "no dependents", edges: [][2]string{{"a", "b"}}, changedPath: "a", wantModules: []string{"a"}},
+		{name: "multiple dependents on same module", edges: [][2]string{{"a", "c"}, {"b", "c"}}, changedPath: "c", wantModules: []string{"a", "b", "c"}},
+		{name: "diamond dependency", edges: [][2]string{{"a", "b"}, {"a", "c"}, {"b", "d"}, {"c", "d"}}, changedPath: "d", wantModules: []string{"a", "b", "c", "d"}},
+		{name: "unknown module returns only itself", edges: [][2]string{{"a", "b"}}, changedPath: "unknown", wantModules: []string{"unknown"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewDependencyGraph()
+			for _, e := range tt.edges {
+				g.AddDependency(e[0], e[1])
+			}
+
+			got := g.GetAffectedModules(tt.changedPath)
+			sort.Strings(got)
+			sort.Strings(tt.wantModules)
+
+			if len(got) != len(tt.wantModules) {
+				t.Errorf("got %v, want %v", got, tt.wantModules)
+				return
+			}
+			for i := range got {
+				if got[i] != tt.wantModules[i] {
+					t.Errorf("got %v, want %v", got, tt.wantModules)
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestAffectedModulesBySource(t *testing.T) {
+	g := NewDependencyGraph()
+	g.AddDependency("a", "c")
+	g.AddDependency("b", "c")
+	g.AddDependency("b", "d")
+	g.AddDependency("c", "e")
+	g.AddDependency("d", "e")
+	g.AddDependencyKind("x", "a", EdgeRemoteState)
+	g.AddDependency("f", "g")
+	g.AddDependency("g", "f")
+
+	tests := []struct {
+		name  string
+		paths []string
+		kinds []EdgeKind
+		want  map[string][]string
+	}{
+		{
+			name:  "sources are attributed in the given order",
+			paths: []string{"d", "c"},
+			want:  map[string][]string{"a": {"c"}, "b": {"d", "c"}, "c": {"c"}, "d": {"d"}, "x": {"c"}},
+		},
+		{
+			name:  "shared dependencies reach every dependent",
+			paths: []string{"e"},
+			kinds: []EdgeKind{EdgeModuleCall},
+			want:  map[string][]string{"a": {"e"}, "b": {"e"}, "c": {"e"}, "d": {"e"}, "e": {"e"}},
+		},
+		{
+			name:  "cycles terminate",
+			paths: []string{"f"},
+			want:  map[string][]string{"f": {"f"}, "g": {"f"}},
+		},
+		{
+			name:  "unknown paths map to themselves",
+			paths: []string{"unknown"},
+			want:  map[string][]string{"unknown": {"unknown"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := g.AffectedModulesBySource(tt.paths, tt.kinds...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AffectedModulesBySource(%v) = %v, want %v", tt.paths, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAffectedModulesBySource_ManySources(t *testing.T) {
+	// More than 64 sources need more than one word per bitset.
+	g := NewDependencyGraph()
+	var paths []string
+	for i := range 100 {
+		path := fmt.Sprintf("modules/m%03d", i)
+		g.AddDependency("root", path)
+		paths = append(paths, path)
+	}
+
+	got := g.AffectedModulesBySource(paths)
+	if !reflect.DeepEqual(got["root"], paths) {
+		t.Errorf("root reached by %v, want %v", got["root"], paths)
+	}
+}
+
+func TestClosureMemo(t *testing.T) {
+	g := NewDependencyGraph()
+	g.EnableClosureMemo()
+	g.AddDependency("a", "b")
+
+	if got := g.GetAffectedModules("b"); !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Fatalf("GetAffectedModules(b) = %v, want [b a]", got)
+	}
+	g.AddDependency("c", "a")
+	if got := g.GetAffectedModules("b"); !reflect.DeepEqual(got, []string{"b", "a", "c"}) {
+		t.Errorf("GetAffectedModules(b) after adding an edge = %v, want [b a c]", got)
+	}
+}
+
+// syntheticGraph builds a graph resembling a large monorepo: a tenth of the modules are shared
+// leaf modules, three tenths are intermediate modules calling three modules each, and the rest
+// are root modules calling five intermediate modules each.
+func syntheticGraph(n int) (*DependencyGraph, []string) {
+	r := rand.New(rand.NewPCG(1, 2))
+	leaves := make([]string, n/10)
+	for i := range leaves {
+		leaves[i] = fmt.Sprintf("modules/shared/m%d", i)
+	}
+	mids := make([]string, n*3/10)
+	for i := range mids {
+		mids[i] = fmt.Sprintf("modules/services/m%d", i)
+	}
+
+	g := NewDependencyGraph()
+	for i, mid := range mids {
+		for range 3 {
+			if j := r.IntN(len(mids)); j < i && r.IntN(2) == 0 {
+				g.AddDependency(mid, mids[j])
+			} else {
+				g.AddDependency(mid, leaves[r.IntN(len(leaves))])
+			}
+		}
+	}
+	for i := range n - len(leaves) - len(mids) {
+		root := fmt.Sprintf("environments/e%d/root", i)
+		for range 5 {
+			g.AddDependency(root, mids[r.IntN(len(mids))])
+		}
+	}
+	return g, append(leaves, mids...)
+}
+
+var benchmarkSizes = []int{1_000, 10_000, 50_000}
+
+func BenchmarkAddDependency(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprintf("modules=%d", n), func(b *testing.B) {
+			for b.Loop() {
+				syntheticGraph(n)
+			}
+		})
+	}
+}
+
+func BenchmarkAffectedModulesBySource(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		g, modules := syntheticGraph(n)
+		changed := modules[:min(300, len(modules))]
+		b.Run(fmt.Sprintf("modules=%d", n), func(b *testing.B) {
+			for b.Loop() {
+				g.AffectedModulesBySource(changed)
+			}
+		})
+	}
+}
+
+// BenchmarkGetAffectedModulesPerSource computes the same result as BenchmarkAffectedModulesBySource
+// with one traversal per changed path.
+func BenchmarkGetAffectedModulesPerSource(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		g, modules := syntheticGraph(n)
+		changed := modules[:min(300, len(modules))]
+		b.Run(fmt.Sprintf("modules=%d", n), func(b *testing.B) {
+			for b.Loop() {
+				bySource := make(map[string][]string)
+				for _, path := range changed {
+					for _, module := range g.GetAffectedModules(path) {
+						bySource[module] = append(bySource[module], path)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetAffectedModulesMemo(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		g, modules := syntheticGraph(n)
+		g.EnableClosureMemo()
+		changed := modules[:min(300, len(modules))]
+		b.Run(fmt.Sprintf("modules=%d", n), func(b *testing.B) {
+			for b.Loop() {
+				for _, path := range changed {
+					g.GetAffectedModules(path)
+				}
+			}
+		})
+	}
+}
+
+func TestGetAllModules(t *testing.T) {
+	g := NewDependencyGraph()
+	g.AddDependency("a", "b")
+	g.AddDependency("b", "c")
+	g.AddDependency("d", "c")
+
+	got := g.GetAllModules()
+	sort.Strings(got)
+
+	want := []string{"a", "b", "c", "d"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestDetectCircularDependencies(t *testing.T) {
+	tests := []struct {
+		name       string
+		edges      [][2]string
+		wantCycles bool
+	}{
+		{name: "no cycles", edges: [][2]string{{"a", "b"}, {"b", "c"}}, wantCycles: false},
+		{name: "direct cycle", edges: [][2]string{{"a", "b"}, {"b", "a"}}, wantCycles: true},
+		{name: "indirect cycle", edges: [][2]string{{"a", "b"}, {"b", "c"}, {"c", "a"}}, wantCycles: true},
+		{name: "self-loop", edges: [][2]string{{"a", "a"}}, wantCycles: true},
+		{name: "empty graph", edges: nil, wantCycles: false},
+		{
+			name:       "cycle in dependents-only module",
+			edges:      [][2]string{{"a", "b"}, {"b", "c"}, {"c", "b"}},
+			wantCycles: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewDependencyGraph()
+			for _, e := range tt.edges {
+				g.AddDependency(e[0], e[1])
+			}
+
+			cycles := g.DetectCircularDependencies()
+			hasCycles := len(cycles) > 0
+			if hasCycles != tt.wantCycles {
+				t.Errorf("DetectCircularDependencies() found cycles = %v, want %v (cycles: %v)", hasCycles, tt.wantCycles, cycles)
+			}
+		})
+	}
+}
+
+func TestGraphString(t *testing.T) {
+	g := NewDependencyGraph()
+	g.AddDependency("a", "b")
+
+	s := g.String()
+	if s == "" {
+		t.Error("String() returned empty string")
+	}
+	if !strings.Contains(s, "a") || !strings.Contains(s, "b") {
+		t.Errorf("String() = %q, expected to contain 'a' and 'b'", s)
+	}
+}
+
+func TestEdgeKinds(t *testing.T) {
+	g := NewDependencyGraph()
+	g.AddDependency("a", "b")
+	g.AddDependencyKind("a", "files/policy.json", EdgeFileReference)
+	g.AddDependencyKind("c", "assets/", EdgeFileReference)
+	g.AddDependencyKind("a", "b", EdgeFileReference)
+
+	if got := g.Kind("a", "b"); got != EdgeModuleCall {
+		t.Errorf("Kind(a, b) = %q, want %q", got, EdgeModuleCall)
+	}
+	if got := g.Kind("a", "files/policy.json"); got != EdgeFileReference {
+		t.Errorf("Kind(a, files/policy.json) = %q, want %q", got, EdgeFileReference)
+	}
+	if got := g.Kind("b", "a"); got != "" {
+		t.Errorf("Kind(b, a) = %q, want empty", got)
+	}
+
+	got := g.FileReferences()
+	want := []string{"assets", "files/policy.json"}
+	if len(got) != len(want) {
+		t.Fatalf("FileReferences() = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("FileReferences() = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package tarm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// DefaultIgnoreFile is the name of the file, in the root directory, read for ignore rules by default.
+const DefaultIgnoreFile = ".tarmignore"
+
+// terraformIgnoreFile is the file Terraform reads for files to leave out of uploaded configuration.
+const terraformIgnoreFile = ".terraformignore"
+
+// defaultTerraformIgnore are the rules Terraform applies before those of a .terraformignore file:
+// .git and .terraform directories are left out, except for the modules installed in .terraform.
+var defaultTerraformIgnore = []string{".git/", "**/.terraform/*", "!**/.terraform/modules/"}
+
+// ignoreRule is a pattern in gitignore syntax.
+type ignoreRule struct {
+	// pattern is a doublestar pattern relative to the directory the rule belongs to.
+	pattern string
+	negate  bool
+	dirOnly bool
+	// source locates the rule, e.g. ".tarmignore:3: *.md".
+	source string
+}
+
+// ignoreRules are the rules of one ignore file, or of the configured patterns.
+type ignoreRules struct {
+	// dir is the absolute directory the patterns are relative to.
+	dir   string
+	rules []ignoreRule
+}
+
+// parseIgnoreRules parses lines in gitignore syntax: blank lines and lines starting with "#" are
+// skipped, "!" negates a pattern, a trailing "/" matches directories only, and a pattern
+// containing any other "/" is relative to dir rather than matching at any depth.
+// location returns the source of the rule on the given line.
+func parseIgnoreRules(dir string, lines []string, location func(line int) string) ignoreRules {
+	r := ignoreRules{dir: dir}
+	for i, line := range lines {
+		text := strings.TrimRight(line, " \t")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rule := ignoreRule{source: fmt.Sprintf("%s: %s", location(i+1), text)}
+		if strings.HasPrefix(text, "!") {
+			rule.negate = true
+			text = text[1:]
+		} else if strings.HasPrefix(text, `\!`) || strings.HasPrefix(text, `\#`) {
+			text = text[1:]
+		}
+		if strings.HasSuffix(text, "/") {
+			rule.dirOnly = true
+			text = strings.TrimRight(text, "/")
+		}
+		if strings.Contains(text, "/") {
+			text = strings.TrimPrefix(text, "/")
+		} else {
+			text = "**/" + text
+		}
+		if text == "" {
+			continue
+		}
+		rule.pattern = text
+		r.rules = append(r.rules, rule)
+	}
+	return r
+}
+
+// readIgnoreFile reads the rules of an ignore file, relative to the directory containing it.
+// A missing file has no rules. name is the file as reported in rule sources.
+func readIgnoreFile(path, name string) (ignoreRules, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ignoreRules{}, nil
+	}
+	if err != nil {
+		return ignoreRules{}, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return ignoreRules{}, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return parseIgnoreRules(filepath.Dir(path), lines, func(line int) string {
+		return fmt.Sprintf("%s:%d", name, line)
+	}), nil
+}
+
+// match returns the last rule matching path, which is absolute, and whether it ignores the path.
+func (r ignoreRules) match(path string, isDir bool) (*ignoreRule, bool) {
+	rel, err := filepath.Rel(r.dir, path)
+	if err != nil || rel == "." || !IsWithinDirectory(path, r.dir) {
+		return nil, false
+	}
+	rel = filepath.ToSlash(rel)
+	for i := len(r.rules) - 1; i >= 0; i-- {
+		rule := &r.rules[i]
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if matched, err := doublestar.Match(rule.pattern, rel); err == nil && matched {
+			return rule, !rule.negate
+		}
+	}
+	return nil, false
+}
+
+// ignorer decides which changed paths are ignored.
+type ignorer struct {
+	root string
+	// rules are the configured rules, in increasing order of precedence.
+	rules []ignoreRules
+	// terraformIgnore enables the .terraformignore files of the modules within root.
+	terraformIgnore bool
+	// terraformRules are the default and .terraformignore rules of each module directory read.
+	terraformRules map[string][]ignoreRules
+	// moduleDirs caches whether a directory contains configuration files.
+	moduleDirs map[string]bool
+}
+
+// newIgnorer returns the ignorer for the ignore settings in cfg, whose paths are relative to root.
+func newIgnorer(cfg Config, root string) (*ignorer, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	ig := &ignorer{
+		root:            absRoot,
+		terraformIgnore: cfg.TerraformIgnore,
+		terraformRules:  make(map[string][]ignoreRules),
+		moduleDirs:      make(map[string]bool),
+	}
+
+	if cfg.IgnoreFile != "" {
+		path := cfg.IgnoreFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(absRoot, path)
+		}
+		rules, err := readIgnoreFile(path, cfg.IgnoreFile)
+		if err != nil {
+			return nil, err
+		}
+		ig.rules = append(ig.rules, rules)
+	}
+	if len(cfg.IgnorePatterns) > 0 {
+		ig.rules = append(ig.rules, parseIgnoreRules(absRoot, cfg.IgnorePatterns, func(int) string { return "ignore pattern" }))
+	}
+	return ig, nil
+}
+
+// enabled reports whether any rules may apply.
+func (ig *ignorer) enabled() bool {
+	return len(ig.rules) > 0 || ig.terraformIgnore
+}
+
+// ignored returns the source of the rule ignoring the changed path, which is relative to the
+// root directory or absolute. As in git, a path within an ignored directory cannot be re-included.
+func (ig *ignorer) ignored(path string) (string, bool, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(ig.root, path)
+	}
+	if !IsWithinDirectory(path, ig.root) {
+		return "", false, nil
+	}
+
+	rel, err := filepath.Rel(ig.root, path)
+	if err != nil {
+		return "", false, nil
+	}
+	rules, err := ig.rulesFor(path)
+	if err != nil {
+		return "", false, err
+	}
+	segments := strings.Split(rel, string(filepath.Separator))
+	for i := range segments {
+		target := filepath.Join(ig.root, filepath.Join(segments[:i+1]...))
+		var last *ignoreRule
+		var ignore bool
+		for _, r := range rules {
+			if rule, ok := r.match(target, i < len(segments)-1); rule != nil {
+				last, ignore = rule, ok
+			}
+		}
+		if ignore {
+			return last.source, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// rulesFor returns the rules applying to the absolute path, in increasing order of precedence:
+// the configured rules, then Terraform's default rules and the .terraformignore file of the module
+// the path belongs to. As in Terraform, which reads the .terraformignore file of the configuration
+// it uploads only, the files of parent directories do not apply.
+func (ig *ignorer) rulesFor(path string) ([]ignoreRules, error) {
+	if !ig.terraformIgnore {
+		return ig.rules, nil
+	}
+	dir, err := ig.moduleDir(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	r, ok := ig.terraformRules[dir]
+	if !ok {
+		name, err := filepath.Rel(ig.root, filepath.Join(dir, terraformIgnoreFile))
+		if err != nil {
+			name = filepath.Join(dir, terraformIgnoreFile)
+		}
+		file, err := readIgnoreFile(filepath.Join(dir, terraformIgnoreFile), filepath.ToSlash(name))
+		if err != nil {
+			return nil, err
+		}
+		defaults := parseIgnoreRules(dir, defaultTerraformIgnore, func(int) string { return "terraformignore default" })
+		r = []ignoreRules{defaults, file}
+		ig.terraformRules[dir] = r
+	}
+	return append(slices.Clone(ig.rules), r...), nil
+}
+
+// moduleDir returns the nearest directory from dir up to the root directory that contains
+// configuration files, or the root directory if none does. Directories that do not exist,
+// such as those of deleted files, are skipped.
+func (ig *ignorer) moduleDir(dir string) (string, error) {
+	for current := dir; current != ig.root && IsWithinDirectory(current, ig.root); current = filepath.Dir(current) {
+		isModule, ok := ig.moduleDirs[current]
+		if !ok {
+			var err error
+			isModule, err = containsTerraformFiles(current)
+			if errors.Is(err, fs.ErrNotExist) {
+				isModule, err = false, nil
+			}
+			if err != nil {
+				return "", err
+			}
+			ig.moduleDirs[current] = isModule
+		}
+		if isModule {
+			return current, nil
+		}
+	}
+	return ig.root, nil
+}
+
+// ignoreChanges splits the changed paths into those to analyze and those matched by the ignore
+// rules in cfg.
+func ignoreChanges(cfg Config, changedPaths []string) ([]string, []ChangedFile, error) {
+	root := cfg.Root
+	if root == "" {
+		root = "."
+	}
+	ig, err := newIgnorer(cfg, root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read ignore rules: %w", err)
+	}
+	if !ig.enabled() {
+		return changedPaths, nil, nil
+	}
+
+	var kept []string
+	var ignored []ChangedFile
+	for _, p := range changedPaths {
+		source, ok, err := ig.ignored(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read ignore rules: %w", err)
+		}
+		if ok {
+			ignored = append(ignored, ChangedFile{Path: p, Status: ChangeIgnored, IgnoredBy: source})
+		} else {
+			kept = append(kept, p)
+		}
+	}
+	return kept, ignored, nil
+}
+
+// ignoredFiles returns the changed files matched by an ignore rule.
+func ignoredFiles(files []ChangedFile) []ChangedFile {
+	var ignored []ChangedFile
+	for _, f := range files {
+		if f.Status == ChangeIgnored {
+			ignored = append(ignored, f)
+		}
+	}
+	return ignored
+}
+
+// rebaseIgnorePatterns converts ignore patterns relative to the top level of a repository into
+// ones relative to the root directory. Patterns without a slash match at any depth and are kept
+// as is; anchored patterns that cannot match within the root directory are dropped.
+func rebaseIgnorePatterns(r *repoPaths, patterns []string) []string {
+	rebased := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		text := strings.TrimRight(p, " \t")
+		var prefix, suffix string
+		if strings.HasPrefix(text, "!") {
+			prefix, text = "!", text[1:]
+		}
+		if strings.HasSuffix(text, "/") {
+			suffix, text = "/", strings.TrimRight(text, "/")
+		}
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, `\`) || !strings.Contains(text, "/") {
+			rebased = append(rebased, p)
+			continue
+		}
+		rp, ok := r.pattern(strings.TrimPrefix(text, "/"))
+		if !ok {
+			continue
+		}
+		if rp == "." {
+			// The pattern matches the root directory itself, so everything within it.
+			rp, suffix = "**", ""
+		}
+		rebased = append(rebased, prefix+"/"+rp+suffix)
+	}
+	return rebased
+}
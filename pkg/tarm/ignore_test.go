@@ -0,0 +1,198 @@
+package tarm
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIgnorer(t *testing.T) {
+	root := t.TempDir()
+
+	tests := []struct {
+		name       string
+		patterns   []string
+		path       string
+		wantSource string
+	}{
+		{name: "file name at any depth", patterns: []string{"*.md"}, path: "modules/network/README.md", wantSource: "ignore pattern: *.md"},
+		{name: "no match", patterns: []string{"*.md"}, path: "modules/network/main.tf"},
+		{name: "anchored pattern", patterns: []string{"/docs/*.md"}, path: "docs/index.md", wantSource: "ignore pattern: /docs/*.md"},
+		{name: "anchored pattern in another directory", patterns: []string{"/docs/*.md"}, path: "modules/docs/index.md"},
+		{name: "pattern with a slash is anchored", patterns: []string{"docs/*.md"}, path: "modules/docs/index.md"},
+		{name: "double star", patterns: []string{"modules/**/*.json"}, path: "modules/network/tests/fixture.json", wantSource: "ignore pattern: modules/**/*.json"},
+		{name: "directory", patterns: []string{"tests/"}, path: "modules/network/tests/fixture.json", wantSource: "ignore pattern: tests/"},
+		{name: "directory pattern does not match files", patterns: []string{"tests/"}, path: "modules/network/tests"},
+		{name: "negation", patterns: []string{"*.md", "!CHANGELOG.md"}, path: "modules/network/CHANGELOG.md"},
+		{name: "later pattern wins", patterns: []string{"!CHANGELOG.md", "*.md"}, path: "modules/network/CHANGELOG.md", wantSource: "ignore pattern: *.md"},
+		{name: "negation cannot re-include a file in an ignored directory", patterns: []string{"tests/", "!tests/*.json"}, path: "tests/fixture.json", wantSource: "ignore pattern: tests/"},
+		{name: "comments and blank lines", patterns: []string{"# *.md", "", "  "}, path: "README.md"},
+		{name: "escaped hash", patterns: []string{`\#notes`}, path: "#notes", wantSource: `ignore pattern: \#notes`},
+		{name: "outside the root directory", patterns: []string{"*.md"}, path: "../README.md"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ig, err := newIgnorer(Config{IgnorePatterns: tt.patterns}, root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			source, ignored, err := ig.ignored(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ignored != (tt.wantSource != "") || source != tt.wantSource {
+				t.Errorf("ignored(%q) = %q, %t, want %q", tt.path, source, ignored, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestIgnorer_TerraformIgnore(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".terraformignore":                   "*.md\n",
+		"modules/network/main.tf":            "",
+		"modules/network/.terraformignore":   "tests/\n",
+		"modules/network/tests/fixture.json": "{}",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantSource string
+	}{
+		{name: "file outside any module", path: "docs/index.md", wantSource: ".terraformignore:1: *.md"},
+		{name: "parent file does not apply to a module", path: "modules/network/README.md"},
+		{name: "module file", path: "modules/network/tests/fixture.json", wantSource: "modules/network/.terraformignore:1: tests/"},
+		{name: "deleted module falls back to the root directory", path: "modules/removed/README.md", wantSource: ".terraformignore:1: *.md"},
+		{name: "default .terraform", path: "modules/network/.terraform/terraform.tfstate", wantSource: "terraformignore default: **/.terraform/*"},
+		{name: "default keeps installed modules", path: "modules/network/.terraform/modules/vpc/main.tf"},
+		{name: "default .git", path: "docs/.git/config", wantSource: "terraformignore default: .git/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ig, err := newIgnorer(Config{TerraformIgnore: true}, root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			source, ignored, err := ig.ignored(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ignored != (tt.wantSource != "") || source != tt.wantSource {
+				t.Errorf("ignored(%q) = %q, %t, want %q", tt.path, source, ignored, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestRebaseIgnorePatterns(t *testing.T) {
+	r := &repoPaths{topLevel: "/repo", root: "/repo/infrastructure", prefix: "infrastructure"}
+
+	got := rebaseIgnorePatterns(r, []string{"*.md", "infrastructure/docs/", "!/infrastructure/modules/*/README.md", "docs/*.md", "**/tests/", "infrastructure/", "/infrastructure/"})
+	want := []string{"*.md", "/docs/", "!/modules/*/README.md", "/**/tests/", "infrastructure/", "/**"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rebaseIgnorePatterns() = %q, want %q", got, want)
+	}
+}
+
+func TestRun_IgnoredFiles(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform-ignore")
+	changedFiles := []string{"modules/network/README.md", "modules/network/CHANGELOG.md", "modules/network/tests/fixture.json"}
+
+	tests := []struct {
+		name            string
+		ignorePatterns  []string
+		ignoreFile      string
+		terraformIgnore bool
+		wantAffected    []string
+		wantIgnored     []ChangedFile
+	}{
+		{
+			name:         "no ignore rules",
+			wantAffected: []string{"environments/dev", "environments/prd"},
+		},
+		{
+			name:         "ignore file",
+			ignoreFile:   DefaultIgnoreFile,
+			wantAffected: []string{"environments/dev", "environments/prd"},
+			wantIgnored: []ChangedFile{
+				{Path: "modules/network/README.md", Status: ChangeIgnored, IgnoredBy: ".tarmignore:2: *.md"},
+			},
+		},
+		{
+			name:            "terraformignore",
+			ignoreFile:      DefaultIgnoreFile,
+			terraformIgnore: true,
+			wantAffected:    []string{"environments/dev", "environments/prd"},
+			wantIgnored: []ChangedFile{
+				{Path: "modules/network/README.md", Status: ChangeIgnored, IgnoredBy: ".tarmignore:2: *.md"},
+				{Path: "modules/network/tests/fixture.json", Status: ChangeIgnored, IgnoredBy: "modules/network/.terraformignore:1: tests/"},
+			},
+		},
+		{
+			name:            "patterns take precedence over the ignore file",
+			ignorePatterns:  []string{"!README.md", "CHANGELOG.md"},
+			ignoreFile:      DefaultIgnoreFile,
+			terraformIgnore: true,
+			wantAffected:    []string{"environments/dev", "environments/prd"},
+			wantIgnored: []ChangedFile{
+				{Path: "modules/network/CHANGELOG.md", Status: ChangeIgnored, IgnoredBy: "ignore pattern: CHANGELOG.md"},
+				{Path: "modules/network/tests/fixture.json", Status: ChangeIgnored, IgnoredBy: "modules/network/.terraformignore:1: tests/"},
+			},
+		},
+		{
+			name:            "every changed file ignored",
+			ignorePatterns:  []string{"modules/network/*", "!modules/network/*.tf"},
+			terraformIgnore: true,
+			wantIgnored: []ChangedFile{
+				{Path: "modules/network/CHANGELOG.md", Status: ChangeIgnored, IgnoredBy: "ignore pattern: modules/network/*"},
+				{Path: "modules/network/README.md", Status: ChangeIgnored, IgnoredBy: "ignore pattern: modules/network/*"},
+				{Path: "modules/network/tests/fixture.json", Status: ChangeIgnored, IgnoredBy: "modules/network/.terraformignore:1: tests/"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Root:               testRoot,
+				RootModulePatterns: []string{"environments/*"},
+				ChangedFiles:       changedFiles,
+				IgnorePatterns:     tt.ignorePatterns,
+				IgnoreFile:         tt.ignoreFile,
+				TerraformIgnore:    tt.terraformIgnore,
+			}
+			result, err := Run(t.Context(), cfg, nil)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			var affected []string
+			for _, m := range result.AffectedModules {
+				affected = append(affected, m.Path)
+			}
+			if !reflect.DeepEqual(affected, tt.wantAffected) {
+				t.Errorf("affected modules = %v, want %v", affected, tt.wantAffected)
+			}
+			if got := result.IgnoredFiles(); !reflect.DeepEqual(got, tt.wantIgnored) {
+				t.Errorf("IgnoredFiles() = %+v, want %+v", got, tt.wantIgnored)
+			}
+			if got := len(result.ChangedFiles); got != len(changedFiles) {
+				t.Errorf("len(ChangedFiles) = %d, want %d", got, len(changedFiles))
+			}
+		})
+	}
+}
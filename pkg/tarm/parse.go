@@ -0,0 +1,267 @@
+package tarm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+)
+
+// Configuration file extensions. OpenTofu additionally reads .tofu and .tofu.json files,
+// where foo.tofu shadows foo.tf and foo.tofu.json shadows foo.tf.json.
+const (
+	extTerraform     = ".tf"
+	extTerraformJSON = ".tf.json"
+	extTofu          = ".tofu"
+	extTofuJSON      = ".tofu.json"
+)
+
+// configExt returns the configuration file extension of name, or an empty string
+// if name is not a Terraform or OpenTofu configuration file.
+func configExt(name string) string {
+	for _, ext := range []string{extTerraformJSON, extTofuJSON, extTerraform, extTofu} {
+		if strings.HasSuffix(name, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// isModuleFile reports whether a file name marks its directory as a module.
+// Every Terraform and OpenTofu configuration file type is recognised regardless of mode.
+func isModuleFile(name string) bool {
+	return configExt(name) != "" || name == TerragruntConfigFile
+}
+
+// configFiles returns the configuration files in dir that Terraform, or OpenTofu when
+// tofu is true, would load. Hidden and editor backup files are ignored like Terraform does.
+func configFiles(dir string, tofu bool) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	present := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		present[entry.Name()] = true
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || isIgnoredConfigFile(name) || isShadowedName(name, present, tofu) {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files, nil
+}
+
+// isShadowedName reports whether the configuration file name is not loaded, either because it
+// is an OpenTofu file outside OpenTofu mode or because a same-named OpenTofu file shadows it.
+func isShadowedName(name string, present map[string]bool, tofu bool) bool {
+	switch ext := configExt(name); ext {
+	case "":
+		return true
+	case extTofu, extTofuJSON:
+		return !tofu
+	default:
+		if !tofu {
+			return false
+		}
+		tofuExt := extTofu
+		if ext == extTerraformJSON {
+			tofuExt = extTofuJSON
+		}
+		return present[strings.TrimSuffix(name, ext)+tofuExt]
+	}
+}
+
+// isShadowedFile reports whether path is a configuration file that is not loaded in the given mode.
+// Paths that are not configuration files are never shadowed.
+func isShadowedFile(path string, tofu bool) bool {
+	name := filepath.Base(path)
+	if configExt(name) == "" {
+		return false
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return false
+	}
+	present := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		present[entry.Name()] = true
+	}
+	return isShadowedName(name, present, tofu)
+}
+
+func isIgnoredConfigFile(name string) bool {
+	return strings.HasPrefix(name, ".") ||
+		strings.HasSuffix(name, "~") ||
+		strings.HasPrefix(name, "#") && strings.HasSuffix(name, "#")
+}
+
+// parseTerraformFiles parses the native syntax configuration files in dir and returns their bodies.
+// Files that fail to parse are skipped; tfconfig reports those errors separately.
+func parseTerraformFiles(dir string, tofu bool) ([]*hclsyntax.Body, error) {
+	bodies, _, err := parseModuleFiles(dir, tofu)
+	return bodies, err
+}
+
+// parseModuleFiles parses the native syntax configuration files in dir and returns their bodies.
+// It also reports whether the module consists only of native syntax files that parsed without
+// errors and are not override files, in which case its module calls can be read from the bodies.
+func parseModuleFiles(dir string, tofu bool) ([]*hclsyntax.Body, bool, error) {
+	files, err := configFiles(dir, tofu)
+	if err != nil {
+		return nil, false, err
+	}
+
+	parser := hclparse.NewParser()
+
+	simple := true
+	var bodies []*hclsyntax.Body
+	for _, filename := range files {
+		if ext := configExt(filename); ext != extTerraform && ext != extTofu {
+			simple = false
+			continue
+		}
+		if isOverrideFile(filename) {
+			simple = false
+		}
+
+		file, diags := parser.ParseHCLFile(filename)
+		if diags.HasErrors() {
+			simple = false
+			continue
+		}
+		if body, ok := file.Body.(*hclsyntax.Body); ok {
+			bodies = append(bodies, body)
+		}
+	}
+
+	return bodies, simple, nil
+}
+
+// isOverrideFile reports whether path is an override file, which Terraform merges into
+// the blocks of the other files instead of loading on its own.
+func isOverrideFile(path string) bool {
+	name := strings.TrimSuffix(filepath.Base(path), configExt(path))
+	return name == "override" || strings.HasSuffix(name, "_override")
+}
+
+// moduleCallsFromBodies reads the module calls from parsed native syntax files as tfconfig does,
+// without decoding the rest of the configuration. It reports false if a module block is malformed
+// or its source or version is not a static string, leaving the module for tfconfig to diagnose.
+func moduleCallsFromBodies(bodies []*hclsyntax.Body) (map[string]*tfconfig.ModuleCall, bool) {
+	calls := make(map[string]*tfconfig.ModuleCall)
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type != "module" {
+				continue
+			}
+			if len(block.Labels) != 1 {
+				return nil, false
+			}
+
+			defRange := block.DefRange()
+			call := &tfconfig.ModuleCall{
+				Name: block.Labels[0],
+				Pos:  tfconfig.SourcePos{Filename: defRange.Filename, Line: defRange.Start.Line},
+			}
+			for name, value := range map[string]*string{"source": &call.Source, "version": &call.Version} {
+				attr, ok := block.Body.Attributes[name]
+				if !ok {
+					continue
+				}
+				if diags := gohcl.DecodeExpression(attr.Expr, nil, value); diags.HasErrors() {
+					return nil, false
+				}
+			}
+			calls[call.Name] = call
+		}
+	}
+	return calls, true
+}
+
+// loadModule loads the module in dir with tfconfig. In OpenTofu mode the .tofu and
+// .tofu.json files are presented to tfconfig under their Terraform names, replacing
+// the files they shadow, and source positions are mapped back to the real file names.
+func loadModule(dir string, tofu bool) (*tfconfig.Module, tfconfig.Diagnostics) {
+	if !tofu {
+		return tfconfig.LoadModule(dir)
+	}
+
+	fsys := &tofuFS{renamed: make(map[string]string)}
+	module, diags := tfconfig.LoadModuleFromFilesystem(fsys, dir)
+	if module != nil {
+		for _, call := range module.ModuleCalls {
+			if original, ok := fsys.renamed[call.Pos.Filename]; ok {
+				call.Pos.Filename = original
+			}
+		}
+	}
+	return module, diags
+}
+
+// tofuFS is a tfconfig.FS that exposes a directory as OpenTofu would load it.
+type tofuFS struct {
+	// renamed maps presented file paths to the OpenTofu files they stand for.
+	renamed map[string]string
+}
+
+func (f *tofuFS) Open(name string) (tfconfig.File, error) {
+	return os.Open(f.real(name))
+}
+
+func (f *tofuFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(f.real(name))
+}
+
+func (f *tofuFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	files, err := configFiles(dirname, true)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]os.FileInfo, 0, len(files))
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		name := filepath.Base(path)
+		switch configExt(name) {
+		case extTofu:
+			name = strings.TrimSuffix(name, extTofu) + extTerraform
+		case extTofuJSON:
+			name = strings.TrimSuffix(name, extTofuJSON) + extTerraformJSON
+		default:
+			infos = append(infos, info)
+			continue
+		}
+		f.renamed[filepath.Join(dirname, name)] = path
+		infos = append(infos, renamedFileInfo{FileInfo: info, name: name})
+	}
+	return infos, nil
+}
+
+func (f *tofuFS) real(name string) string {
+	if original, ok := f.renamed[name]; ok {
+		return original
+	}
+	return name
+}
+
+type renamedFileInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (i renamedFileInfo) Name() string { return i.name }
@@ -0,0 +1,184 @@
+package tarm
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsModuleFile(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"main.tf", true},
+		{"main.tf.json", true},
+		{"main.tofu", true},
+		{"main.tofu.json", true},
+		{"terragrunt.hcl", true},
+		{"config.yaml", false},
+		{"main.tfvars", false},
+		{"schema.json", false},
+	}
+	for _, tt := range tests {
+		if got := isModuleFile(tt.name); got != tt.want {
+			t.Errorf("isModuleFile(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConfigFiles(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "opentofu")
+
+	tests := []struct {
+		name string
+		dir  string
+		tofu bool
+		want []string
+	}{
+		{name: "terraform ignores tofu files", dir: "environments/app", want: []string{"main.tf"}},
+		{name: "tofu file shadows tf file", dir: "environments/app", tofu: true, want: []string{"main.tofu"}},
+		{name: "json configuration", dir: "environments/json", want: []string{"main.tf.json"}},
+		{name: "tofu mode reads both kinds", dir: "environments/mixed", tofu: true, want: []string{"encryption.tofu", "main.tf"}},
+		{name: "tofu-only module in terraform mode", dir: "modules/encryption", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := configFiles(filepath.Join(testRoot, tt.dir), tt.tofu)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, f := range files {
+				got = append(got, filepath.Base(f))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadModule_OpenTofu(t *testing.T) {
+	dir, _ := filepath.Abs(filepath.Join("..", "..", "testdata", "opentofu", "environments", "app"))
+
+	module, diags := loadModule(dir, true)
+	if diags.HasErrors() {
+		t.Fatalf("loadModule() error = %v", diags)
+	}
+
+	call, ok := module.ModuleCalls["encryption"]
+	if !ok || len(module.ModuleCalls) != 1 {
+		t.Fatalf("ModuleCalls = %v, want only encryption", module.ModuleCalls)
+	}
+	if want := filepath.Join(dir, "main.tofu"); call.Pos.Filename != want {
+		t.Errorf("Pos.Filename = %q, want %q", call.Pos.Filename, want)
+	}
+}
+
+func TestModuleCallsFromBodies(t *testing.T) {
+	// Every module in the fixtures that can be read without tfconfig must yield the same module calls.
+	var dirs []string
+	filepath.WalkDir(filepath.Join("..", "..", "testdata"), func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return err
+	})
+
+	compared := 0
+	for _, dir := range dirs {
+		for _, tofu := range []bool{false, true} {
+			bodies, simple, err := parseModuleFiles(dir, tofu)
+			if err != nil || !simple || len(bodies) == 0 {
+				continue
+			}
+			got, ok := moduleCallsFromBodies(bodies)
+			if !ok {
+				continue
+			}
+			module, diags := loadModule(dir, tofu)
+			if diags.HasErrors() {
+				t.Errorf("%s: loadModule() error = %v, want the module to need tfconfig", dir, diags)
+				continue
+			}
+			compared++
+			if len(got) != len(module.ModuleCalls) {
+				t.Errorf("%s: got %d module calls, want %d", dir, len(got), len(module.ModuleCalls))
+			}
+			for name, want := range module.ModuleCalls {
+				if call, ok := got[name]; !ok || *call != *want {
+					t.Errorf("%s: module %q = %+v, want %+v", dir, name, call, want)
+				}
+			}
+		}
+	}
+	if compared == 0 {
+		t.Fatal("no module was read without tfconfig")
+	}
+}
+
+func TestModuleCallsFromBodies_Fallback(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		simple bool
+		ok     bool
+	}{
+		{
+			name:   "static source and version",
+			files:  map[string]string{"main.tf": "module \"a\" {\n  source  = \"../a\"\n  version = \"1.0.0\"\n}\n"},
+			simple: true,
+			ok:     true,
+		},
+		{
+			name:   "source from a variable",
+			files:  map[string]string{"main.tf": "module \"a\" {\n  source = var.source\n}\n"},
+			simple: true,
+			ok:     false,
+		},
+		{
+			name:   "override file",
+			files:  map[string]string{"main.tf": "module \"a\" {\n  source = \"../a\"\n}\n", "main_override.tf": "module \"a\" {\n  source = \"../b\"\n}\n"},
+			simple: false,
+		},
+		{
+			name:   "json file",
+			files:  map[string]string{"main.tf.json": `{"module": {"a": {"source": "../a"}}}`},
+			simple: false,
+		},
+		{
+			name:   "syntax error",
+			files:  map[string]string{"main.tf": "module \"a\" {\n"},
+			simple: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			bodies, simple, err := parseModuleFiles(dir, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if simple != tt.simple {
+				t.Fatalf("simple = %v, want %v", simple, tt.simple)
+			}
+			if !simple {
+				return
+			}
+			if _, ok := moduleCallsFromBodies(bodies); ok != tt.ok {
+				t.Errorf("ok = %v, want %v", ok, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package tarm
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/kzmshx/tarm/pkg/git"
+)
+
+// PathBase is the directory the paths and patterns in a Config are relative to.
+type PathBase string
+
+const (
+	// PathBaseRoot makes paths and patterns relative to the root directory.
+	PathBaseRoot PathBase = "root"
+	// PathBaseRepository makes paths and patterns relative to the top level of the git
+	// repository containing the root directory.
+	PathBaseRepository PathBase = "repository"
+)
+
+// ParsePathBase parses a path base. An empty string selects PathBaseRoot.
+func ParsePathBase(s string) (PathBase, error) {
+	switch PathBase(s) {
+	case "", PathBaseRoot:
+		return PathBaseRoot, nil
+	case PathBaseRepository:
+		return PathBaseRepository, nil
+	default:
+		return "", fmt.Errorf("invalid path base %q: must be root or repository", s)
+	}
+}
+
+// repoPaths relates paths relative to the top level of a git repository to the root directory.
+type repoPaths struct {
+	topLevel string
+	root     string
+	// prefix is the root directory relative to the top level, in slash form.
+	prefix string
+}
+
+// newRepoPaths returns the relation between the top level of a repository and the root
+// directory, which must be within it. Symbolic links are resolved, since git reports the
+// top level with links resolved.
+func newRepoPaths(topLevel, root string) (*repoPaths, error) {
+	realRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(realRoot); err == nil {
+		realRoot = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(topLevel); err == nil {
+		topLevel = resolved
+	}
+	rel, err := filepath.Rel(topLevel, realRoot)
+	if err != nil || !IsWithinDirectory(realRoot, topLevel) {
+		return nil, fmt.Errorf("root directory %s is outside the repository %s", root, topLevel)
+	}
+	return &repoPaths{topLevel: topLevel, root: realRoot, prefix: filepath.ToSlash(rel)}, nil
+}
+
+// path converts a path relative to the top level into one relative to the root directory.
+// Paths outside the root directory start with "..".
+func (r *repoPaths) path(p string) string {
+	rel, err := filepath.Rel(r.root, filepath.Join(r.topLevel, filepath.FromSlash(p)))
+	if err != nil {
+		return p
+	}
+	return rel
+}
+
+// pattern converts a glob pattern relative to the top level into one relative to the root
+// directory. It reports false if the pattern cannot match anything within the root directory.
+func (r *repoPaths) pattern(p string) (string, bool) {
+	if r.prefix == "." {
+		return p, true
+	}
+	segments := strings.Split(path.Clean(p), "/")
+	for i, dir := range strings.Split(r.prefix, "/") {
+		if i >= len(segments) {
+			return "", false
+		}
+		// "**" may match the rest of the prefix as well as directories within the root directory.
+		if segments[i] == "**" {
+			return strings.Join(segments[i:], "/"), true
+		}
+		if matched, err := doublestar.Match(segments[i], dir); err != nil || !matched {
+			return "", false
+		}
+	}
+	rest := segments[len(strings.Split(r.prefix, "/")):]
+	if len(rest) == 0 {
+		return ".", true
+	}
+	return strings.Join(rest, "/"), true
+}
+
+// rebaseConfig makes the paths and patterns in cfg relative to the root directory when they
+// are relative to the repository, and returns the relation used, or nil if there is nothing to do.
+func rebaseConfig(ctx context.Context, cfg Config) (Config, *repoPaths, error) {
+	if cfg.PathBase != PathBaseRepository {
+		return cfg, nil, nil
+	}
+	root := cfg.Root
+	if root == "" {
+		root = "."
+	}
+	topLevel, err := git.TopLevel(ctx, root)
+	if err != nil {
+		return cfg, nil, fmt.Errorf("failed to find the repository: %w", err)
+	}
+	r, err := newRepoPaths(topLevel, root)
+	if err != nil {
+		return cfg, nil, err
+	}
+
+	rebasePatterns := func(kind string, patterns []string) ([]string, error) {
+		rebased := make([]string, 0, len(patterns))
+		for _, p := range patterns {
+			rp, ok := r.pattern(p)
+			if !ok {
+				return nil, fmt.Errorf("%s %q matches nothing within the root directory %s", kind, p, r.prefix)
+			}
+			rebased = append(rebased, rp)
+		}
+		return rebased, nil
+	}
+	rebasePaths := func(paths []string) []string {
+		rebased := make([]string, 0, len(paths))
+		for _, p := range paths {
+			if filepath.IsAbs(p) {
+				rebased = append(rebased, p)
+			} else {
+				rebased = append(rebased, r.path(p))
+			}
+		}
+		return rebased
+	}
+
+	if cfg.RootModulePatterns, err = rebasePatterns("root module pattern", cfg.RootModulePatterns); err != nil {
+		return cfg, nil, err
+	}
+	if cfg.ExcludeModulePatterns, err = rebasePatterns("exclude module pattern", cfg.ExcludeModulePatterns); err != nil {
+		return cfg, nil, err
+	}
+	// Inherited file patterns without a slash match file names anywhere.
+	inherited := make([]string, 0, len(cfg.InheritedFilePatterns))
+	for _, p := range cfg.InheritedFilePatterns {
+		if strings.Contains(p, "/") {
+			rp, ok := r.pattern(p)
+			if !ok {
+				return cfg, nil, fmt.Errorf("inherited file pattern %q matches nothing within the root directory %s", p, r.prefix)
+			}
+			p = rp
+		}
+		inherited = append(inherited, p)
+	}
+	cfg.InheritedFilePatterns = inherited
+
+	if cfg.GlobalTriggers, err = rebaseGlobalTriggers(r, cfg.GlobalTriggers); err != nil {
+		return cfg, nil, err
+	}
+
+	targets := make([]TargetRule, 0, len(cfg.Targets))
+	for _, t := range cfg.Targets {
+		rp, ok := r.pattern(t.RootModulePattern)
+		if !ok {
+			return cfg, nil, fmt.Errorf("target root module pattern %q matches nothing within the root directory %s", t.RootModulePattern, r.prefix)
+		}
+		t.RootModulePattern = rp
+		targets = append(targets, t)
+	}
+	cfg.Targets = targets
+
+	rewrites := make([]SourceRewrite, 0, len(cfg.SourceRewrites))
+	for _, rule := range cfg.SourceRewrites {
+		rule.Path = filepath.ToSlash(r.path(rule.Path))
+		rewrites = append(rewrites, rule)
+	}
+	cfg.SourceRewrites = rewrites
+
+	cfg.IgnorePatterns = rebaseIgnorePatterns(r, cfg.IgnorePatterns)
+	if cfg.IgnoreFile != "" && !filepath.IsAbs(cfg.IgnoreFile) {
+		cfg.IgnoreFile = r.path(cfg.IgnoreFile)
+	}
+	cfg.ChangedFiles = rebasePaths(cfg.ChangedFiles)
+	cfg.FailOnUnmapped = rebasePaths(cfg.FailOnUnmapped)
+	return cfg, r, nil
+}
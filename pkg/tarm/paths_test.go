@@ -0,0 +1,185 @@
+package tarm
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kzmshx/tarm/pkg/git"
+)
+
+func TestParsePathBase(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    PathBase
+		wantErr bool
+	}{
+		{input: "", want: PathBaseRoot},
+		{input: "root", want: PathBaseRoot},
+		{input: "repository", want: PathBaseRepository},
+		{input: "repo", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePathBase(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePathBase(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePathBase(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestRepoPaths(t *testing.T) {
+	topLevel := t.TempDir()
+	root := filepath.Join(topLevel, "infrastructure")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	r, err := newRepoPaths(topLevel, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patterns := []struct {
+		pattern string
+		want    string
+		wantOK  bool
+	}{
+		{pattern: "infrastructure/environments/*/*", want: "environments/*/*", wantOK: true},
+		{pattern: "*/environments/*", want: "environments/*", wantOK: true},
+		{pattern: "**/environments/*", want: "**/environments/*", wantOK: true},
+		{pattern: "infra*/**/prod", want: "**/prod", wantOK: true},
+		{pattern: "infrastructure", want: ".", wantOK: true},
+		{pattern: "environments/*", wantOK: false},
+		{pattern: "other/*", wantOK: false},
+	}
+	for _, tt := range patterns {
+		got, ok := r.pattern(tt.pattern)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("pattern(%q) = %q, %t, want %q, %t", tt.pattern, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	paths := map[string]string{
+		"infrastructure/modules/a/main.tf": "modules/a/main.tf",
+		"infrastructure":                   ".",
+		"scripts/deploy.sh":                "../scripts/deploy.sh",
+	}
+	for p, want := range paths {
+		if got := r.path(p); got != want {
+			t.Errorf("path(%q) = %q, want %q", p, got, want)
+		}
+	}
+
+	if _, err := newRepoPaths(root, topLevel); err == nil {
+		t.Error("expected error for a root directory outside the repository")
+	}
+}
+
+func TestRun_Repository(t *testing.T) {
+	repo := t.TempDir()
+	root := filepath.Join(repo, "infrastructure")
+	if err := os.CopyFS(root, os.DirFS(filepath.Join("..", "..", "testdata", "terraform"))); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd := func(args ...string) {
+		t.Helper()
+		args = append([]string{"-C", repo, "-c", "user.name=tarm", "-c", "user.email=tarm@example.com"}, args...)
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	gitCmd("init", "-q")
+	gitCmd("add", "-A")
+	gitCmd("commit", "-q", "-m", "base")
+	gitCmd("tag", "base")
+	f, err := os.OpenFile(filepath.Join(root, "modules", "database", "main.tf"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("\n# changed\n")
+	f.Close()
+	if err := os.WriteFile(filepath.Join(repo, "deploy.sh"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd("add", "-A")
+	gitCmd("commit", "-q", "-m", "head")
+
+	wantModules := []string{"environments/dev/api", "environments/stg/api"}
+	tests := []struct {
+		name      string
+		cfg       Config
+		provider  git.ChangedFilesProvider
+		wantFiles []ChangedFile
+		wantErr   bool
+	}{
+		{
+			name:     "detected changes are relative to the repository",
+			cfg:      Config{Root: root, RootModulePatterns: []string{"environments/*/*"}, DetectChanges: true},
+			provider: &git.DiffProvider{BaseRef: "base", Dir: root},
+			wantFiles: []ChangedFile{
+				{Path: "../deploy.sh", Status: ChangeOutsideRoot},
+				{Path: "modules/database/main.tf", Status: ChangeMapped, Sources: []Cause{{Path: "modules/database", Reason: ReasonModule}}},
+			},
+		},
+		{
+			name:     "detected changes of a wrapped provider are relative to the repository",
+			cfg:      Config{Root: root, RootModulePatterns: []string{"environments/*/*"}, DetectChanges: true},
+			provider: &git.MultiProvider{Providers: []git.ChangedFilesProvider{&git.StaticProvider{}, &git.DiffProvider{BaseRef: "base", Dir: root}}},
+			wantFiles: []ChangedFile{
+				{Path: "../deploy.sh", Status: ChangeOutsideRoot},
+				{Path: "modules/database/main.tf", Status: ChangeMapped, Sources: []Cause{{Path: "modules/database", Reason: ReasonModule}}},
+			},
+		},
+		{
+			name: "paths and patterns relative to the repository",
+			cfg: Config{
+				Root:               root,
+				PathBase:           PathBaseRepository,
+				RootModulePatterns: []string{"infrastructure/environments/*/*"},
+				ChangedFiles:       []string{"infrastructure/modules/database/main.tf"},
+			},
+			wantFiles: []ChangedFile{
+				{Path: "modules/database/main.tf", Status: ChangeMapped, Sources: []Cause{{Path: "modules/database", Reason: ReasonModule}}},
+			},
+		},
+		{
+			name: "pattern outside the root directory",
+			cfg: Config{
+				Root:               root,
+				PathBase:           PathBaseRepository,
+				RootModulePatterns: []string{"environments/*/*"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Run(t.Context(), tt.cfg, tt.provider)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			var got []string
+			for _, m := range result.AffectedModules {
+				got = append(got, m.Path)
+			}
+			if !reflect.DeepEqual(got, wantModules) {
+				t.Errorf("affected modules = %v, want %v", got, wantModules)
+			}
+			if !reflect.DeepEqual(result.ChangedFiles, tt.wantFiles) {
+				t.Errorf("ChangedFiles = %+v, want %+v", result.ChangedFiles, tt.wantFiles)
+			}
+		})
+	}
+}
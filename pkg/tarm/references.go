@@ -0,0 +1,166 @@
+package tarm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// fileFunctions are the Terraform functions whose first argument is a path read from disk.
+var fileFunctions = map[string]bool{
+	"file":             true,
+	"filebase64":       true,
+	"filebase64sha256": true,
+	"filebase64sha512": true,
+	"filemd5":          true,
+	"fileset":          true,
+	"filesha1":         true,
+	"filesha256":       true,
+	"filesha512":       true,
+	"templatefile":     true,
+}
+
+// archiveFileArguments are the archive_file data source arguments that point to local files.
+var archiveFileArguments = []string{"source_dir", "source_file"}
+
+// FindFileReferences returns the absolute paths of files and directories referenced
+// by the module in dir through file functions and archive_file data sources.
+// Only paths that can be resolved statically against path.module, path.root or
+// path.cwd are returned. The module is treated as a root module, so path.root, path.cwd and
+// the directory relative paths are resolved against are dir itself.
+func FindFileReferences(dir string) ([]string, error) {
+	bodies, err := parseTerraformFiles(dir, false)
+	if err != nil {
+		return nil, err
+	}
+	return findFileReferences(dir, bodies), nil
+}
+
+func findFileReferences(dir string, bodies []*hclsyntax.Body) []string {
+	candidates, rootRefs := fileReferenceCandidates(dir, bodies)
+	for _, rel := range rootRefs {
+		candidates = append(candidates, filepath.Join(dir, rel))
+	}
+
+	var refs []string
+	for _, path := range Unique(candidates) {
+		if _, err := os.Stat(path); err == nil {
+			refs = append(refs, path)
+		}
+	}
+	return refs
+}
+
+// fileReferenceCandidates returns the absolute paths referenced by the module in dir relative
+// to path.module, and the paths referenced relative to path.root or path.cwd, which depend on
+// the root module the module is called from, whether or not they exist. Relative paths are
+// resolved by Terraform against the working directory, so they are treated as relative to path.cwd.
+func fileReferenceCandidates(dir string, bodies []*hclsyntax.Body) (refs, rootRefs []string) {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	ctx := pathEvalContext(dir)
+
+	for _, body := range bodies {
+		var exprs []hclsyntax.Expression
+		hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+			call, ok := node.(*hclsyntax.FunctionCallExpr)
+			if ok && fileFunctions[call.Name] && len(call.Args) > 0 {
+				exprs = append(exprs, call.Args[0])
+			}
+			return nil
+		})
+
+		for _, block := range body.Blocks {
+			if block.Type != "data" || len(block.Labels) != 2 || block.Labels[0] != "archive_file" {
+				continue
+			}
+			for _, name := range archiveFileArguments {
+				if attr, ok := block.Body.Attributes[name]; ok {
+					exprs = append(exprs, attr.Expr)
+				}
+			}
+		}
+
+		for _, expr := range exprs {
+			path, ok := evalStaticPath(expr, ctx)
+			if !ok {
+				continue
+			}
+			if !filepath.IsAbs(path) && !strings.HasPrefix(path, rootPlaceholder) {
+				path = rootPlaceholder + "/" + path
+			}
+			if rel, ok := strings.CutPrefix(path, rootPlaceholder); ok {
+				if rel = filepath.Clean(strings.TrimPrefix(rel, "/")); rel != "." {
+					rootRefs = append(rootRefs, rel)
+				}
+				continue
+			}
+			path = filepath.Clean(path)
+			if path == filepath.Clean(dir) {
+				continue
+			}
+			refs = append(refs, path)
+		}
+	}
+
+	return Unique(refs), Unique(rootRefs)
+}
+
+// rootPlaceholder stands for path.root and path.cwd while evaluating paths, so that paths
+// relative to the root module can be told apart from those relative to the module itself.
+const rootPlaceholder = "\x00root"
+
+// pathEvalContext returns an evaluation context that defines the path object for a module in dir.
+func pathEvalContext(dir string) *hcl.EvalContext {
+	rootVal := cty.StringVal(rootPlaceholder)
+	return &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"path": cty.ObjectVal(map[string]cty.Value{
+				"module": cty.StringVal(dir),
+				"root":   rootVal,
+				"cwd":    rootVal,
+			}),
+		},
+	}
+}
+
+// evalStaticPath evaluates a path expression. When the expression is a template
+// containing unknown parts, the directory of its static prefix is returned instead.
+func evalStaticPath(expr hclsyntax.Expression, ctx *hcl.EvalContext) (string, bool) {
+	if s, ok := evalString(expr, ctx); ok {
+		return s, s != ""
+	}
+
+	tmpl, ok := expr.(*hclsyntax.TemplateExpr)
+	if !ok {
+		return "", false
+	}
+
+	var prefix strings.Builder
+	for _, part := range tmpl.Parts {
+		s, ok := evalString(part, ctx)
+		if !ok {
+			break
+		}
+		prefix.WriteString(s)
+	}
+
+	i := strings.LastIndex(prefix.String(), "/")
+	if i <= 0 {
+		return "", false
+	}
+	return prefix.String()[:i], true
+}
+
+func evalString(expr hcl.Expression, ctx *hcl.EvalContext) (string, bool) {
+	v, diags := expr.Value(ctx)
+	if diags.HasErrors() || !v.IsWhollyKnown() || v.IsNull() || v.Type() != cty.String {
+		return "", false
+	}
+	return v.AsString(), true
+}
@@ -0,0 +1,49 @@
+package tarm
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestFindFileReferences(t *testing.T) {
+	testRoot, _ := filepath.Abs(filepath.Join("..", "..", "testdata", "terraform-assets"))
+
+	tests := []struct {
+		name     string
+		dir      string
+		wantRefs []string
+	}{
+		{name: "archive_file source_dir and file()", dir: "modules/lambda", wantRefs: []string{"modules/lambda/src/handler", "policies/lambda.json"}},
+		{name: "templatefile, fileset and dynamic filemd5 path", dir: "environments/prod", wantRefs: []string{"environments/prod/templates/user_data.tpl", "scripts"}},
+		{name: "module without file references", dir: "environments/dev", wantRefs: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs, err := FindFileReferences(filepath.Join(testRoot, tt.dir))
+			if err != nil {
+				t.Fatalf("FindFileReferences() error = %v", err)
+			}
+
+			var got []string
+			for _, ref := range refs {
+				rel, err := filepath.Rel(testRoot, ref)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, rel)
+			}
+			sort.Strings(got)
+
+			if len(got) != len(tt.wantRefs) {
+				t.Fatalf("got %v, want %v", got, tt.wantRefs)
+			}
+			for i := range got {
+				if got[i] != tt.wantRefs[i] {
+					t.Fatalf("got %v, want %v", got, tt.wantRefs)
+				}
+			}
+		})
+	}
+}
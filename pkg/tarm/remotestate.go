@@ -0,0 +1,160 @@
+package tarm
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// StateLocation identifies where Terraform state is stored, either as the backend
+// of a root module or as the target of a terraform_remote_state data source.
+type StateLocation struct {
+	// Backend is the backend type, e.g. "s3". The cloud block is treated as the "remote" backend.
+	Backend string
+	// Config holds the statically known backend configuration. Nested blocks and
+	// objects are flattened into dotted keys, e.g. "workspaces.name".
+	Config map[string]string
+}
+
+// backendIdentityAttributes lists, per backend type, the configuration attributes that
+// together identify a state. Backends not listed here are identified by all of their
+// statically known attributes.
+var backendIdentityAttributes = map[string][]string{
+	"s3":      {"bucket", "key"},
+	"gcs":     {"bucket", "prefix"},
+	"azurerm": {"storage_account_name", "container_name", "key"},
+	"local":   {"path"},
+	"remote":  {"organization", "workspaces.name"},
+	"consul":  {"path"},
+	"http":    {"address"},
+}
+
+// Key returns a string identifying the state. It returns an empty string when the
+// location is not specific enough to be matched, e.g. for partial backend configuration.
+func (l StateLocation) Key() string {
+	attrs, ok := backendIdentityAttributes[l.Backend]
+	if !ok {
+		for attr := range l.Config {
+			attrs = append(attrs, attr)
+		}
+		sort.Strings(attrs)
+	}
+	if len(attrs) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(attrs))
+	for _, attr := range attrs {
+		v, ok := l.Config[attr]
+		if !ok || v == "" {
+			return ""
+		}
+		parts = append(parts, attr+"="+v)
+	}
+	return l.Backend + ":" + strings.Join(parts, ",")
+}
+
+// findBackend returns the state location configured by the terraform block's backend
+// or cloud block, or nil if the module does not configure one.
+func findBackend(dir string, bodies []*hclsyntax.Body) *StateLocation {
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type != "terraform" {
+				continue
+			}
+			for _, inner := range block.Body.Blocks {
+				var loc StateLocation
+				switch {
+				case inner.Type == "backend" && len(inner.Labels) == 1:
+					loc = StateLocation{Backend: inner.Labels[0], Config: map[string]string{}}
+				case inner.Type == "cloud":
+					loc = StateLocation{Backend: "remote", Config: map[string]string{}}
+				default:
+					continue
+				}
+				collectStaticBody(inner.Body, "", loc.Config)
+				resolveLocalState(dir, &loc)
+				return &loc
+			}
+		}
+	}
+	return nil
+}
+
+// findRemoteStates returns the state locations read by terraform_remote_state data sources.
+func findRemoteStates(dir string, bodies []*hclsyntax.Body) []StateLocation {
+	var locs []StateLocation
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type != "data" || len(block.Labels) != 2 || block.Labels[0] != "terraform_remote_state" {
+				continue
+			}
+
+			backendAttr, ok := block.Body.Attributes["backend"]
+			if !ok {
+				continue
+			}
+			backend, ok := evalString(backendAttr.Expr, nil)
+			if !ok {
+				continue
+			}
+
+			loc := StateLocation{Backend: backend, Config: map[string]string{}}
+			if configAttr, ok := block.Body.Attributes["config"]; ok {
+				collectStaticObject(configAttr.Expr, "", loc.Config)
+			}
+			resolveLocalState(dir, &loc)
+			locs = append(locs, loc)
+		}
+	}
+	return locs
+}
+
+// resolveLocalState makes the path of a local backend absolute so that locations
+// written from different directories can be compared.
+func resolveLocalState(dir string, loc *StateLocation) {
+	if loc.Backend != "local" {
+		return
+	}
+	path := loc.Config["path"]
+	if path == "" {
+		path = "terraform.tfstate"
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	loc.Config["path"] = filepath.Clean(path)
+}
+
+func collectStaticBody(body *hclsyntax.Body, prefix string, out map[string]string) {
+	for name, attr := range body.Attributes {
+		collectStaticObject(attr.Expr, prefix+name, out)
+	}
+	for _, block := range body.Blocks {
+		collectStaticBody(block.Body, prefix+block.Type+".", out)
+	}
+}
+
+func collectStaticObject(expr hclsyntax.Expression, key string, out map[string]string) {
+	obj, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		if v, ok := evalString(expr, nil); ok {
+			out[key] = v
+		}
+		return
+	}
+
+	prefix := ""
+	if key != "" {
+		prefix = key + "."
+	}
+	for _, item := range obj.Items {
+		name, ok := evalString(item.KeyExpr, nil)
+		if !ok {
+			continue
+		}
+		collectStaticObject(item.ValueExpr, prefix+name, out)
+	}
+}
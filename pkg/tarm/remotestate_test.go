@@ -0,0 +1,78 @@
+package tarm
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStateLocationKey(t *testing.T) {
+	tests := []struct {
+		name string
+		loc  StateLocation
+		want string
+	}{
+		{name: "s3 uses bucket and key", loc: StateLocation{Backend: "s3", Config: map[string]string{"bucket": "b", "key": "k", "region": "r"}}, want: "s3:bucket=b,key=k"},
+		{name: "partial s3 config is not matchable", loc: StateLocation{Backend: "s3", Config: map[string]string{"bucket": "b"}}, want: ""},
+		{name: "remote uses flattened workspace name", loc: StateLocation{Backend: "remote", Config: map[string]string{"organization": "o", "workspaces.name": "w"}}, want: "remote:organization=o,workspaces.name=w"},
+		{name: "unknown backend uses all attributes", loc: StateLocation{Backend: "custom", Config: map[string]string{"b": "2", "a": "1"}}, want: "custom:a=1,b=2"},
+		{name: "unknown backend without config", loc: StateLocation{Backend: "custom", Config: map[string]string{}}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.loc.Key(); got != tt.want {
+				t.Errorf("Key() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindBackendAndRemoteStates(t *testing.T) {
+	testRoot, _ := filepath.Abs(filepath.Join("..", "..", "testdata", "terraform-remote-state"))
+
+	tests := []struct {
+		name           string
+		dir            string
+		wantBackend    string
+		wantRemoteKeys []string
+	}{
+		{name: "s3 backend", dir: "stacks/shared/vpc", wantBackend: "s3:bucket=example-tfstate,key=shared/vpc.tfstate"},
+		{name: "local backend resolves path", dir: "stacks/shared/dns", wantBackend: "local:path=" + filepath.Join(testRoot, "state", "dns.tfstate")},
+		{name: "cloud block", dir: "apps/batch", wantBackend: "remote:organization=example,workspaces.name=batch", wantRemoteKeys: []string{""}},
+		{
+			name:           "multiple remote states",
+			dir:            "apps/api",
+			wantBackend:    "s3:bucket=example-tfstate,key=apps/api.tfstate",
+			wantRemoteKeys: []string{"s3:bucket=example-tfstate,key=apps/web.tfstate", "local:path=" + filepath.Join(testRoot, "state", "dns.tfstate")},
+		},
+		{name: "no backend", dir: "apps/report", wantRemoteKeys: []string{"remote:organization=example,workspaces.name=batch"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(testRoot, tt.dir)
+			bodies, err := parseTerraformFiles(dir, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var gotBackend string
+			if backend := findBackend(dir, bodies); backend != nil {
+				gotBackend = backend.Key()
+			}
+			if gotBackend != tt.wantBackend {
+				t.Errorf("backend key = %q, want %q", gotBackend, tt.wantBackend)
+			}
+
+			states := findRemoteStates(dir, bodies)
+			if len(states) != len(tt.wantRemoteKeys) {
+				t.Fatalf("got %d remote states, want %d", len(states), len(tt.wantRemoteKeys))
+			}
+			for i, state := range states {
+				if got := state.Key(); got != tt.wantRemoteKeys[i] {
+					t.Errorf("remote state %d key = %q, want %q", i, got, tt.wantRemoteKeys[i])
+				}
+			}
+		})
+	}
+}
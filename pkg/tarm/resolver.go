@@ -27,7 +27,7 @@ func NormalizePath(root, path string) (string, error) {
 // ResolveModuleSource resolves a module source path relative to the calling module.
 // Returns empty string for non-local sources (registry, git, http, etc.).
 func ResolveModuleSource(callerDir, source string) (string, error) {
-	if !isLocalPath(source) {
+	if ParseModuleSource(source).IsRemote() {
 		return "", nil
 	}
 
@@ -35,31 +35,8 @@ func ResolveModuleSource(callerDir, source string) (string, error) {
 	return filepath.Clean(absPath), nil
 }
 
-func isLocalPath(source string) bool {
-	nonLocalPrefixes := []string{
-		"git::",
-		"github.com/",
-		"bitbucket.org/",
-		"http://",
-		"https://",
-		"s3::",
-		"gcs::",
-	}
-
-	for _, prefix := range nonLocalPrefixes {
-		if strings.HasPrefix(source, prefix) {
-			return false
-		}
-	}
-
-	if strings.Count(source, "/") >= 2 && !strings.HasPrefix(source, "./") && !strings.HasPrefix(source, "../") && !strings.HasPrefix(source, "/") {
-		return false
-	}
-
-	return true
-}
-
-// FindParentWithTerraformFiles finds the nearest parent directory containing .tf files.
+// FindParentWithTerraformFiles finds the nearest parent directory containing Terraform or
+// OpenTofu configuration files (.tf, .tf.json, .tofu, .tofu.json) or a terragrunt.hcl.
 func FindParentWithTerraformFiles(startPath, rootDir string) (string, error) {
 	if !filepath.IsAbs(startPath) {
 		absPath, err := filepath.Abs(startPath)
@@ -93,7 +70,7 @@ func FindParentWithTerraformFiles(startPath, rootDir string) (string, error) {
 		}
 
 		for _, entry := range entries {
-			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".tf") {
+			if !entry.IsDir() && isModuleFile(entry.Name()) {
 				return current, nil
 			}
 		}
@@ -48,6 +48,11 @@ func TestResolveModuleSource(t *testing.T) {
 		{name: "https source returns empty", callerDir: "/repo", source: "https://example.com/module.zip", wantEmpty: true},
 		{name: "s3 source returns empty", callerDir: "/repo", source: "s3::https://bucket/module.zip", wantEmpty: true},
 		{name: "gcs source returns empty", callerDir: "/repo", source: "gcs::https://bucket/module.zip", wantEmpty: true},
+		{name: "hg source returns empty", callerDir: "/repo", source: "hg::http://example.com/vpc.hg", wantEmpty: true},
+		{name: "oci source returns empty", callerDir: "/repo", source: "oci://registry.example.com/org/module", wantEmpty: true},
+		{name: "ssh source returns empty", callerDir: "/repo", source: "git@github.com:org/module.git", wantEmpty: true},
+		{name: "registry with host returns empty", callerDir: "/repo", source: "app.terraform.io/org/module/aws", wantEmpty: true},
+		{name: "local path with subdirectory", callerDir: "/repo/live/prod", source: "../../modules//vpc", wantPath: "/repo/modules/vpc"},
 	}
 
 	for _, tt := range tests {
@@ -123,3 +128,28 @@ func TestIsWithinDirectory(t *testing.T) {
 		})
 	}
 }
+
+func TestFindParentWithTerraformFiles_ConfigFileTypes(t *testing.T) {
+	testRoot, _ := filepath.Abs(filepath.Join("..", "..", "testdata", "opentofu"))
+
+	tests := []struct {
+		name    string
+		start   string
+		wantDir string
+	}{
+		{name: "tf.json only directory", start: filepath.Join(testRoot, "modules", "storage"), wantDir: filepath.Join(testRoot, "modules", "storage")},
+		{name: "tofu only directory", start: filepath.Join(testRoot, "modules", "encryption", "main.tofu"), wantDir: filepath.Join(testRoot, "modules", "encryption")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindParentWithTerraformFiles(tt.start, testRoot)
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got != tt.wantDir {
+				t.Errorf("got %q, want %q", got, tt.wantDir)
+			}
+		})
+	}
+}
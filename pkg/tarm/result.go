@@ -0,0 +1,100 @@
+package tarm
+
+import (
+	"bufio"
+	"strings"
+)
+
+// AffectedRootModule represents a root module affected by changes.
+type AffectedRootModule struct {
+	Path       string   `json:"path"`
+	AffectedBy []string `json:"affected_by"`
+	Causes     []Cause  `json:"causes,omitempty"`
+	// Chains are the shortest dependency chains from the root module to each cause it does not contain directly.
+	Chains []Chain `json:"chains,omitempty"`
+	// Deleted is true when the root module no longer exists after the change.
+	Deleted bool `json:"deleted,omitempty"`
+	// Targets are the affected deployment targets, for root modules matched by a target rule.
+	Targets []Target `json:"targets,omitempty"`
+}
+
+// Reason describes how a change reaches an affected root module.
+type Reason string
+
+const (
+	// ReasonModule means the change is in the root module or in a module it calls.
+	ReasonModule Reason = "module"
+	// ReasonFileReference means the change is in a file or directory read by the root module
+	// or by a module it calls.
+	ReasonFileReference Reason = "file"
+	// ReasonRemoteState means the root module reads, through terraform_remote_state,
+	// the state of another root module affected by the change.
+	ReasonRemoteState Reason = "remote_state"
+	// ReasonDependency means the Terragrunt unit depends, through a dependency or
+	// dependencies block, on another unit affected by the change.
+	ReasonDependency Reason = "dependency"
+	// ReasonVarFile means the change is in the var file of one of the root module's targets.
+	ReasonVarFile Reason = "var_file"
+	// ReasonInherited means the change is in an inherited file, e.g. a shared backend.hcl,
+	// in the root module's directory or one of its ancestors.
+	ReasonInherited Reason = "inherited"
+	// ReasonParseError means the root module, or a module it calls, failed to parse and
+	// the parse error policy treats such modules as affected.
+	ReasonParseError Reason = "parse_error"
+	// ReasonGlobalTrigger means the change is in a file matching a global trigger, e.g. a CI
+	// workflow, that affects the root module regardless of its dependencies.
+	ReasonGlobalTrigger Reason = "global_trigger"
+)
+
+// Cause is a path that affects a root module together with the reason it does so.
+type Cause struct {
+	Path   string `json:"path"`
+	Reason Reason `json:"reason"`
+}
+
+// ReasonFor returns the reason recorded for the given cause path, or ReasonModule if none is recorded.
+func (m AffectedRootModule) ReasonFor(path string) Reason {
+	for _, c := range m.Causes {
+		if c.Path == path {
+			return c.Reason
+		}
+	}
+	return ReasonModule
+}
+
+// ChainsFor returns the dependency chains leading to the given cause path.
+func (m AffectedRootModule) ChainsFor(path string) []Chain {
+	var chains []Chain
+	for _, c := range m.Chains {
+		if c.Cause == path {
+			chains = append(chains, c)
+		}
+	}
+	return chains
+}
+
+// Unique returns a new slice with duplicate elements removed, preserving order.
+func Unique[T comparable](slice []T) []T {
+	seen := make(map[T]bool)
+	var result []T
+	for _, v := range slice {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// ParseMultilineInput parses a newline-separated string into a slice of trimmed, non-empty lines.
+func ParseMultilineInput(input string) []string {
+	var result []string
+	scanner := bufio.NewScanner(strings.NewReader(input))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
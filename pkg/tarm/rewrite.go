@@ -0,0 +1,104 @@
+package tarm
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// SourceRewrite maps remote module sources that point back into the repository, such as
+// git URLs of the repository itself or a private registry namespace, to a local directory.
+type SourceRewrite struct {
+	// Pattern matches the module source without its subdirectory and query, e.g.
+	// "git::https://github.com/our-org/infra.git" or "app.terraform.io/our-org/{name}/aws".
+	// A {name} placeholder matches one path segment and "*" matches any characters except "/".
+	Pattern string
+	// Path is the local directory, relative to the root directory, that matching sources map to.
+	// Placeholders captured by Pattern are substituted, and the source's subdirectory is appended.
+	Path string
+	// FloatingOnly follows the rewrite only when the source refers to a floating ref, such as a
+	// branch or a version constraint, rather than a pinned tag, commit or exact version.
+	FloatingOnly bool
+}
+
+// ParseSourceRewrite parses a rule of the form "<pattern>=<path>[;floating-only]", e.g.
+// "git::https://github.com/our-org/infra.git=." or "app.terraform.io/our-org/{name}/aws=modules/{name};floating-only".
+func ParseSourceRewrite(s string) (SourceRewrite, error) {
+	spec, options, _ := strings.Cut(s, ";")
+	i := strings.LastIndex(spec, "=")
+	if i <= 0 || i == len(spec)-1 {
+		return SourceRewrite{}, fmt.Errorf("invalid source rewrite %q: expected <pattern>=<path>", s)
+	}
+
+	rule := SourceRewrite{
+		Pattern: strings.TrimSpace(spec[:i]),
+		Path:    strings.TrimSpace(spec[i+1:]),
+	}
+	for _, option := range strings.Split(options, ";") {
+		switch strings.TrimSpace(option) {
+		case "":
+		case "floating-only":
+			rule.FloatingOnly = true
+		default:
+			return SourceRewrite{}, fmt.Errorf("invalid source rewrite %q: unknown option %q", s, option)
+		}
+	}
+
+	if _, err := rule.regexp(); err != nil {
+		return SourceRewrite{}, fmt.Errorf("invalid source rewrite %q: %w", s, err)
+	}
+	return rule, nil
+}
+
+// Rewrite returns the local directory, relative to the root directory, for a module source
+// and its version constraint. It returns false if the rule does not apply.
+func (r SourceRewrite) Rewrite(source, version string) (string, bool) {
+	re, err := r.regexp()
+	if err != nil {
+		return "", false
+	}
+
+	src := ParseModuleSource(source)
+	match := re.FindStringSubmatch(src.Address)
+	if match == nil {
+		return "", false
+	}
+
+	if r.FloatingOnly {
+		ref := src.Ref
+		if ref == "" {
+			ref = version
+		}
+		if isPinnedRef(ref) {
+			return "", false
+		}
+	}
+
+	local := r.Path
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			local = strings.ReplaceAll(local, "{"+name+"}", match[i])
+		}
+	}
+	return path.Join(local, src.Subdir), true
+}
+
+var placeholderPattern = regexp.MustCompile(`\\\{(\w+)\\\}`)
+
+func (r SourceRewrite) regexp() (*regexp.Regexp, error) {
+	expr := regexp.QuoteMeta(strings.TrimSuffix(r.Pattern, "/"))
+	expr = placeholderPattern.ReplaceAllString(expr, `(?P<$1>[^/]+)`)
+	expr = strings.ReplaceAll(expr, `\*`, `[^/]*`)
+	return regexp.Compile("^" + expr + "$")
+}
+
+// pinnedRefPattern matches refs and versions that do not move: version tags and exact
+// version constraints such as "v1.2.3" or "= 1.2.3", and abbreviated or full commit hashes.
+var pinnedRefPattern = regexp.MustCompile(`^(=\s*)?v?\d+\.\d+\.\d+\S*$|^[0-9a-f]{7,40}$`)
+
+// isPinnedRef reports whether a git ref or registry version constraint is pinned.
+// An empty ref follows the default branch or latest version and is therefore floating.
+func isPinnedRef(ref string) bool {
+	return pinnedRefPattern.MatchString(strings.TrimSpace(ref))
+}
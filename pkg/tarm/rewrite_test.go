@@ -0,0 +1,67 @@
+package tarm
+
+import "testing"
+
+func TestParseSourceRewrite(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    SourceRewrite
+		wantErr bool
+	}{
+		{input: "git::https://github.com/our-org/infra.git=.", want: SourceRewrite{Pattern: "git::https://github.com/our-org/infra.git", Path: "."}},
+		{input: "app.terraform.io/our-org/{name}/aws=modules/{name};floating-only", want: SourceRewrite{Pattern: "app.terraform.io/our-org/{name}/aws", Path: "modules/{name}", FloatingOnly: true}},
+		{input: "app.terraform.io/our-org/network/aws", wantErr: true},
+		{input: "=modules/network", wantErr: true},
+		{input: "app.terraform.io/our-org/network/aws=", wantErr: true},
+		{input: "app.terraform.io/our-org/network/aws=modules/network;pinned", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSourceRewrite(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSourceRewrite() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseSourceRewrite() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSourceRewrite_Rewrite(t *testing.T) {
+	git := SourceRewrite{Pattern: "git::https://github.com/our-org/infra.git", Path: "."}
+	registry := SourceRewrite{Pattern: "app.terraform.io/our-org/{name}/*", Path: "modules/{name}"}
+	floating := SourceRewrite{Pattern: "git::https://github.com/our-org/infra.git", Path: ".", FloatingOnly: true}
+	floatingRegistry := SourceRewrite{Pattern: "app.terraform.io/our-org/{name}/aws", Path: "modules/{name}", FloatingOnly: true}
+
+	tests := []struct {
+		name    string
+		rule    SourceRewrite
+		source  string
+		version string
+		want    string
+		wantOK  bool
+	}{
+		{name: "git subdirectory", rule: git, source: "git::https://github.com/our-org/infra.git//modules/network?ref=main", want: "modules/network", wantOK: true},
+		{name: "git without subdirectory", rule: git, source: "git::https://github.com/our-org/infra.git", want: ".", wantOK: true},
+		{name: "other repository", rule: git, source: "git::https://github.com/other-org/infra.git//modules/network", wantOK: false},
+		{name: "registry placeholder", rule: registry, source: "app.terraform.io/our-org/network/aws", want: "modules/network", wantOK: true},
+		{name: "registry submodule", rule: registry, source: "app.terraform.io/our-org/network/aws//modules/subnet", want: "modules/network/modules/subnet", wantOK: true},
+		{name: "floating branch", rule: floating, source: "git::https://github.com/our-org/infra.git//modules/network?ref=main", want: "modules/network", wantOK: true},
+		{name: "default branch", rule: floating, source: "git::https://github.com/our-org/infra.git//modules/network", want: "modules/network", wantOK: true},
+		{name: "pinned tag", rule: floating, source: "git::https://github.com/our-org/infra.git//modules/network?ref=v1.2.3", wantOK: false},
+		{name: "pinned commit", rule: floating, source: "git::https://github.com/our-org/infra.git//modules/network?ref=3f2c1ab", wantOK: false},
+		{name: "version constraint", rule: floatingRegistry, source: "app.terraform.io/our-org/network/aws", version: "~> 1.2", want: "modules/network", wantOK: true},
+		{name: "exact version", rule: floatingRegistry, source: "app.terraform.io/our-org/network/aws", version: "= 1.2.0", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.rule.Rewrite(tt.source, tt.version)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Rewrite(%q, %q) = (%q, %t), want (%q, %t)", tt.source, tt.version, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
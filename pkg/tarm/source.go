@@ -0,0 +1,215 @@
+package tarm
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// SourceKind classifies a module source address.
+type SourceKind string
+
+const (
+	// SourceLocal is a path to a directory in the same repository, e.g. "../modules/vpc".
+	SourceLocal SourceKind = "local"
+	// SourceRegistry is a module registry address, e.g. "hashicorp/consul/aws".
+	SourceRegistry SourceKind = "registry"
+	// SourceGit is a git repository, including the github.com and bitbucket.org shorthands.
+	SourceGit SourceKind = "git"
+	// SourceMercurial is a Mercurial repository.
+	SourceMercurial SourceKind = "hg"
+	// SourceHTTP is an archive or redirect served over HTTP(S).
+	SourceHTTP SourceKind = "http"
+	// SourceS3 is an archive in an S3 bucket.
+	SourceS3 SourceKind = "s3"
+	// SourceGCS is an archive in a Google Cloud Storage bucket.
+	SourceGCS SourceKind = "gcs"
+	// SourceOCI is an artifact in an OCI distribution registry.
+	SourceOCI SourceKind = "oci"
+	// SourceUnknown is any other address.
+	SourceUnknown SourceKind = "unknown"
+)
+
+// DefaultRegistryHost is the registry host of registry addresses that do not name one.
+const DefaultRegistryHost = "registry.terraform.io"
+
+// ModuleSource is a parsed module source address.
+type ModuleSource struct {
+	// Raw is the source as written.
+	Raw  string     `json:"raw"`
+	Kind SourceKind `json:"kind"`
+	// Address is the source without its subdirectory and query.
+	Address string `json:"address"`
+	// Host is the registry host or the host of a remote URL.
+	Host string `json:"host,omitempty"`
+	// Path is the path of a remote URL on its host.
+	Path string `json:"path,omitempty"`
+	// Namespace, Name and System are the parts of a registry address.
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	System    string `json:"system,omitempty"`
+	// Ref is the git ref or Mercurial revision requested in the query.
+	Ref string `json:"ref,omitempty"`
+	// Subdir is the subdirectory within the package, given after a double slash.
+	Subdir string `json:"subdir,omitempty"`
+}
+
+// IsRemote reports whether the source is fetched from outside the repository.
+func (s ModuleSource) IsRemote() bool {
+	return s.Kind != SourceLocal
+}
+
+var (
+	// forcedGetterPattern matches a go-getter forced getter prefix such as "git::".
+	forcedGetterPattern = regexp.MustCompile(`^([A-Za-z0-9]+)::(.+)$`)
+	// scpLikePattern matches SSH URLs in the scp form, e.g. "git@github.com:org/repo.git".
+	scpLikePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+@([A-Za-z0-9.-]+):(.+)$`)
+	// registryPartPattern matches the namespace, name and system parts of a registry address.
+	registryPartPattern = regexp.MustCompile(`^[0-9A-Za-z](?:[0-9A-Za-z_-]{0,62}[0-9A-Za-z])?$`)
+)
+
+// ParseModuleSource parses a module source address following the rules of Terraform and
+// go-getter: local paths first, then forced getters, URLs and shorthands, then registry addresses.
+func ParseModuleSource(source string) ModuleSource {
+	s := ModuleSource{Raw: source, Kind: SourceUnknown}
+
+	if isLocalSourcePath(source) {
+		s.Kind = SourceLocal
+		s.Address, s.Subdir, _ = splitSourceAddress(source)
+		return s
+	}
+
+	address, subdir, query := splitSourceAddress(source)
+	s.Address, s.Subdir = address, subdir
+
+	if m := forcedGetterPattern.FindStringSubmatch(address); m != nil {
+		switch strings.ToLower(m[1]) {
+		case "git":
+			s.Kind = SourceGit
+		case "hg":
+			s.Kind = SourceMercurial
+		case "s3":
+			s.Kind = SourceS3
+		case "gcs":
+			s.Kind = SourceGCS
+		case "http", "https":
+			s.Kind = SourceHTTP
+		}
+		s.Host, s.Path = splitRemoteURL(m[2])
+	} else if scpLikePattern.MatchString(address) {
+		s.Kind = SourceGit
+		s.Host, s.Path = splitRemoteURL(address)
+	} else if scheme, rest, ok := strings.Cut(address, "://"); ok {
+		switch strings.ToLower(scheme) {
+		case "oci":
+			s.Kind = SourceOCI
+		case "http", "https":
+			s.Kind = SourceHTTP
+		case "ssh", "git":
+			s.Kind = SourceGit
+		case "s3":
+			s.Kind = SourceS3
+		case "gs":
+			s.Kind = SourceGCS
+		}
+		s.Host, s.Path = splitRemoteURL(rest)
+	} else if host, p, _ := strings.Cut(address, "/"); host == "github.com" || host == "bitbucket.org" {
+		s.Kind = SourceGit
+		s.Host, s.Path = host, p
+	} else if strings.HasSuffix(host, ".amazonaws.com") && strings.Contains(host, "s3") {
+		s.Kind = SourceS3
+		s.Host, s.Path = host, p
+	} else if host == "www.googleapis.com" && strings.HasPrefix(p, "storage/") {
+		s.Kind = SourceGCS
+		s.Host, s.Path = host, p
+	} else if parseRegistryAddress(address, &s) {
+		s.Kind = SourceRegistry
+	}
+
+	switch s.Kind {
+	case SourceGit:
+		s.Ref = query.Get("ref")
+	case SourceMercurial:
+		s.Ref = query.Get("rev")
+	}
+	return s
+}
+
+// isLocalSourcePath reports whether source is a local path. Like Terraform, relative
+// paths must start with "./" or "../"; absolute paths are accepted as well.
+func isLocalSourcePath(source string) bool {
+	for _, prefix := range []string{"./", "../", ".\\", "..\\", "/"} {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	return source == "." || source == ".."
+}
+
+// splitRemoteURL splits a URL, with or without its scheme, or an scp-like SSH URL
+// into host and path, dropping any user information.
+func splitRemoteURL(rest string) (host, p string) {
+	if m := scpLikePattern.FindStringSubmatch(rest); m != nil {
+		return m[1], strings.TrimPrefix(m[2], "/")
+	}
+	if scheme, after, ok := strings.Cut(rest, "://"); ok && !strings.Contains(scheme, "/") {
+		rest = after
+	}
+	host, p, _ = strings.Cut(rest, "/")
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	return host, p
+}
+
+// parseRegistryAddress fills the registry fields of s if address has the form
+// [<host>/]<namespace>/<name>/<system>.
+func parseRegistryAddress(address string, s *ModuleSource) bool {
+	parts := strings.Split(address, "/")
+	host := DefaultRegistryHost
+	switch len(parts) {
+	case 3:
+	case 4:
+		host = parts[0]
+		if !strings.ContainsAny(host, ".:") {
+			return false
+		}
+		if u, err := url.Parse("https://" + host); err != nil || u.Host != host {
+			return false
+		}
+		parts = parts[1:]
+	default:
+		return false
+	}
+
+	for _, part := range parts {
+		if !registryPartPattern.MatchString(part) {
+			return false
+		}
+	}
+	s.Host = strings.ToLower(host)
+	s.Namespace, s.Name, s.System = parts[0], parts[1], parts[2]
+	return true
+}
+
+// splitSourceAddress splits a module source into its address, the subdirectory after a
+// double slash, and its query parameters, e.g. "git::https://host/repo.git//modules/vpc?ref=main"
+// into "git::https://host/repo.git", "modules/vpc" and ref=main.
+func splitSourceAddress(source string) (address, subdir string, query url.Values) {
+	address, rawQuery, _ := strings.Cut(source, "?")
+	query, _ = url.ParseQuery(rawQuery)
+
+	offset := 0
+	if i := strings.Index(address, "://"); i >= 0 {
+		offset = i + len("://")
+	}
+	if i := strings.Index(address[offset:], "//"); i >= 0 {
+		subdir = address[offset+i+len("//"):]
+		address = address[:offset+i]
+		if subdir != "" {
+			subdir = path.Clean(subdir)
+		}
+	}
+	return strings.TrimSuffix(address, "/"), subdir, query
+}
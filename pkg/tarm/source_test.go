@@ -0,0 +1,93 @@
+package tarm
+
+import "testing"
+
+func TestParseModuleSource(t *testing.T) {
+	tests := []struct {
+		source string
+		want   ModuleSource
+	}{
+		{
+			source: "../../modules/network",
+			want:   ModuleSource{Kind: SourceLocal, Address: "../../modules/network"},
+		},
+		{
+			source: "../../../modules//vpc",
+			want:   ModuleSource{Kind: SourceLocal, Address: "../../../modules", Subdir: "vpc"},
+		},
+		{
+			source: "/repo/modules/network",
+			want:   ModuleSource{Kind: SourceLocal, Address: "/repo/modules/network"},
+		},
+		{
+			source: "hashicorp/consul/aws",
+			want:   ModuleSource{Kind: SourceRegistry, Address: "hashicorp/consul/aws", Host: "registry.terraform.io", Namespace: "hashicorp", Name: "consul", System: "aws"},
+		},
+		{
+			source: "registry.terraform.io/hashicorp/consul/aws//modules/consul-cluster",
+			want:   ModuleSource{Kind: SourceRegistry, Address: "registry.terraform.io/hashicorp/consul/aws", Host: "registry.terraform.io", Namespace: "hashicorp", Name: "consul", System: "aws", Subdir: "modules/consul-cluster"},
+		},
+		{
+			source: "app.terraform.io/our-org/network/aws",
+			want:   ModuleSource{Kind: SourceRegistry, Address: "app.terraform.io/our-org/network/aws", Host: "app.terraform.io", Namespace: "our-org", Name: "network", System: "aws"},
+		},
+		{
+			source: "git::https://github.com/our-org/infra.git//modules/network?ref=v1.2.0",
+			want:   ModuleSource{Kind: SourceGit, Address: "git::https://github.com/our-org/infra.git", Host: "github.com", Path: "our-org/infra.git", Ref: "v1.2.0", Subdir: "modules/network"},
+		},
+		{
+			source: "git::ssh://git@gitlab.example.com/our-org/infra.git?ref=main",
+			want:   ModuleSource{Kind: SourceGit, Address: "git::ssh://git@gitlab.example.com/our-org/infra.git", Host: "gitlab.example.com", Path: "our-org/infra.git", Ref: "main"},
+		},
+		{
+			source: "git@github.com:our-org/infra.git//modules/vpc",
+			want:   ModuleSource{Kind: SourceGit, Address: "git@github.com:our-org/infra.git", Host: "github.com", Path: "our-org/infra.git", Subdir: "modules/vpc"},
+		},
+		{
+			source: "github.com/hashicorp/example?ref=main",
+			want:   ModuleSource{Kind: SourceGit, Address: "github.com/hashicorp/example", Host: "github.com", Path: "hashicorp/example", Ref: "main"},
+		},
+		{
+			source: "bitbucket.org/hashicorp/terraform-consul-aws",
+			want:   ModuleSource{Kind: SourceGit, Address: "bitbucket.org/hashicorp/terraform-consul-aws", Host: "bitbucket.org", Path: "hashicorp/terraform-consul-aws"},
+		},
+		{
+			source: "hg::http://example.com/vpc.hg?rev=v1.2.0",
+			want:   ModuleSource{Kind: SourceMercurial, Address: "hg::http://example.com/vpc.hg", Host: "example.com", Path: "vpc.hg", Ref: "v1.2.0"},
+		},
+		{
+			source: "https://example.com/vpc-module.zip",
+			want:   ModuleSource{Kind: SourceHTTP, Address: "https://example.com/vpc-module.zip", Host: "example.com", Path: "vpc-module.zip"},
+		},
+		{
+			source: "s3::https://s3-eu-west-1.amazonaws.com/examplecorp-terraform-modules/vpc.zip",
+			want:   ModuleSource{Kind: SourceS3, Address: "s3::https://s3-eu-west-1.amazonaws.com/examplecorp-terraform-modules/vpc.zip", Host: "s3-eu-west-1.amazonaws.com", Path: "examplecorp-terraform-modules/vpc.zip"},
+		},
+		{
+			source: "examplecorp-terraform-modules.s3.amazonaws.com/vpc.zip",
+			want:   ModuleSource{Kind: SourceS3, Address: "examplecorp-terraform-modules.s3.amazonaws.com/vpc.zip", Host: "examplecorp-terraform-modules.s3.amazonaws.com", Path: "vpc.zip"},
+		},
+		{
+			source: "gcs::https://www.googleapis.com/storage/v1/modules/foomodule.zip",
+			want:   ModuleSource{Kind: SourceGCS, Address: "gcs::https://www.googleapis.com/storage/v1/modules/foomodule.zip", Host: "www.googleapis.com", Path: "storage/v1/modules/foomodule.zip"},
+		},
+		{
+			source: "oci://registry.example.com/our-org/network",
+			want:   ModuleSource{Kind: SourceOCI, Address: "oci://registry.example.com/our-org/network", Host: "registry.example.com", Path: "our-org/network"},
+		},
+		{
+			source: "modules/network",
+			want:   ModuleSource{Kind: SourceUnknown, Address: "modules/network"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			tt.want.Raw = tt.source
+			got := ParseModuleSource(tt.source)
+			if got != tt.want {
+				t.Errorf("ParseModuleSource(%q) =\n  %+v\nwant\n  %+v", tt.source, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package tarm
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Target is one deployment of a root module, planned with a specific var file and/or workspace.
+type Target struct {
+	VarFile   string `json:"var_file,omitempty"`
+	Workspace string `json:"workspace,omitempty"`
+}
+
+// String returns the target in the key=value form accepted by ParseTargetRule.
+func (t Target) String() string {
+	var parts []string
+	if t.VarFile != "" {
+		parts = append(parts, "var-file="+t.VarFile)
+	}
+	if t.Workspace != "" {
+		parts = append(parts, "workspace="+t.Workspace)
+	}
+	return strings.Join(parts, " ")
+}
+
+// TargetRule expands the root modules matching RootModulePattern into deployment targets.
+//
+// Without Workspaces, every var file matching VarFiles is a target. With Workspaces, every
+// workspace is a target, and a matching var file named after the workspace
+// (e.g. envs/dev.tfvars for workspace dev) belongs to that workspace's target.
+type TargetRule struct {
+	// RootModulePattern is a glob pattern selecting the root modules the rule applies to.
+	RootModulePattern string
+	// VarFiles are glob patterns, relative to the root module, for var files.
+	VarFiles []string
+	// Workspaces are the workspace names the root modules are planned in.
+	Workspaces []string
+}
+
+// ParseTargetRule parses a rule of the form "<root-module-pattern>:<key>=<value>;<key>=<value>...",
+// where key is "var-file" or "workspace" and may be repeated, e.g.
+// "environments/app:var-file=envs/*.tfvars" or "stacks/*:workspace=dev;workspace=prod".
+func ParseTargetRule(s string) (TargetRule, error) {
+	pattern, spec, ok := strings.Cut(s, ":")
+	pattern = strings.TrimSpace(pattern)
+	if !ok || pattern == "" {
+		return TargetRule{}, fmt.Errorf("invalid target rule %q: expected <root-module-pattern>:<key>=<value>", s)
+	}
+
+	rule := TargetRule{RootModulePattern: pattern}
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			return TargetRule{}, fmt.Errorf("invalid target rule %q: expected <key>=<value>, got %q", s, item)
+		}
+		switch strings.TrimSpace(key) {
+		case "var-file":
+			rule.VarFiles = append(rule.VarFiles, value)
+		case "workspace":
+			rule.Workspaces = append(rule.Workspaces, value)
+		default:
+			return TargetRule{}, fmt.Errorf("invalid target rule %q: unknown key %q", s, key)
+		}
+	}
+
+	if len(rule.VarFiles) == 0 && len(rule.Workspaces) == 0 {
+		return TargetRule{}, fmt.Errorf("invalid target rule %q: at least one var-file or workspace is required", s)
+	}
+	return rule, nil
+}
+
+// ExpandTargets returns the targets of every root module matched by the rules, keyed by
+// root module path. Var file paths in the returned targets are relative to the root module.
+// When several rules match a root module, the first one wins.
+func ExpandTargets(fsys fs.FS, rules []TargetRule) (map[string][]Target, error) {
+	targets := make(map[string][]Target)
+	for _, rule := range rules {
+		dirs, err := doublestar.Glob(fsys, rule.RootModulePattern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dir := range dirs {
+			if _, exists := targets[dir]; exists {
+				continue
+			}
+			if info, err := fs.Stat(fsys, dir); err != nil || !info.IsDir() {
+				continue
+			}
+
+			var varFiles []string
+			for _, pattern := range rule.VarFiles {
+				matches, err := doublestar.Glob(fsys, path.Join(dir, pattern), doublestar.WithFilesOnly())
+				if err != nil {
+					return nil, err
+				}
+				for _, m := range matches {
+					varFiles = append(varFiles, strings.TrimPrefix(m, dir+"/"))
+				}
+			}
+			varFiles = Unique(varFiles)
+
+			var dirTargets []Target
+			if len(rule.Workspaces) == 0 {
+				for _, varFile := range varFiles {
+					dirTargets = append(dirTargets, Target{VarFile: varFile})
+				}
+			} else {
+				for _, workspace := range rule.Workspaces {
+					target := Target{Workspace: workspace}
+					for _, varFile := range varFiles {
+						if varFileStem(varFile) == workspace {
+							target.VarFile = varFile
+							break
+						}
+					}
+					dirTargets = append(dirTargets, target)
+				}
+			}
+
+			if len(dirTargets) > 0 {
+				targets[dir] = dirTargets
+			}
+		}
+	}
+	return targets, nil
+}
+
+func varFileStem(varFile string) string {
+	name := path.Base(varFile)
+	for _, ext := range []string{".tfvars.json", ".tfvars"} {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// varFileChange is a changed var file that belongs to a single target of a root module.
+type varFileChange struct {
+	module string
+	path   string
+	target Target
+	// changedPath is the changed path as given.
+	changedPath string
+}
+
+// splitVarFileChanges separates the changed paths that are var files of a target from the rest.
+// Changed paths are relative to root or absolute.
+func splitVarFileChanges(root string, changedPaths []string, targets map[string][]Target) ([]varFileChange, []string) {
+	byPath := make(map[string]varFileChange)
+	for module, moduleTargets := range targets {
+		for _, target := range moduleTargets {
+			if target.VarFile == "" {
+				continue
+			}
+			p := path.Join(module, target.VarFile)
+			byPath[p] = varFileChange{module: module, path: p, target: target}
+		}
+	}
+	if len(byPath) == 0 {
+		return nil, changedPaths
+	}
+
+	var varFiles []varFileChange
+	var rest []string
+	for _, changedPath := range changedPaths {
+		rel := changedPath
+		if filepath.IsAbs(rel) {
+			absRoot, err := filepath.Abs(root)
+			if err == nil {
+				if r, err := filepath.Rel(absRoot, rel); err == nil {
+					rel = r
+				}
+			}
+		}
+		if change, ok := byPath[filepath.ToSlash(filepath.Clean(rel))]; ok {
+			change.changedPath = changedPath
+			varFiles = append(varFiles, change)
+			continue
+		}
+		rest = append(rest, changedPath)
+	}
+	return varFiles, rest
+}
+
+// applyTargets attaches targets to the affected root modules. A root module affected by
+// anything but its var files is affected in every target, while a changed var file only
+// adds the target it belongs to.
+func applyTargets(modules []AffectedRootModule, targets map[string][]Target, varFiles []varFileChange, isRoot func(string) bool) []AffectedRootModule {
+	index := make(map[string]int, len(modules))
+	for i := range modules {
+		index[modules[i].Path] = i
+		if moduleTargets, ok := targets[modules[i].Path]; ok {
+			modules[i].Targets = slices.Clone(moduleTargets)
+		}
+	}
+
+	for _, change := range varFiles {
+		if !isRoot(change.module) {
+			continue
+		}
+		i, ok := index[change.module]
+		if !ok {
+			modules = append(modules, AffectedRootModule{Path: change.module})
+			i = len(modules) - 1
+			index[change.module] = i
+		}
+		m := &modules[i]
+		m.AffectedBy = Unique(append(m.AffectedBy, change.path))
+		m.Causes = Unique(append(m.Causes, Cause{Path: change.path, Reason: ReasonVarFile}))
+		if !slices.Contains(m.Targets, change.target) {
+			m.Targets = append(m.Targets, change.target)
+		}
+	}
+
+	// Keep targets in the order the rule expanded them.
+	for i := range modules {
+		moduleTargets, ok := targets[modules[i].Path]
+		if !ok {
+			continue
+		}
+		var ordered []Target
+		for _, target := range moduleTargets {
+			if slices.Contains(modules[i].Targets, target) {
+				ordered = append(ordered, target)
+			}
+		}
+		modules[i].Targets = ordered
+	}
+
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Path < modules[j].Path })
+	return modules
+}
@@ -0,0 +1,99 @@
+package tarm
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseTargetRule(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    TargetRule
+		wantErr bool
+	}{
+		{
+			input: "services/api:var-file=envs/*.tfvars",
+			want:  TargetRule{RootModulePattern: "services/api", VarFiles: []string{"envs/*.tfvars"}},
+		},
+		{
+			input: "services/*: workspace=dev; workspace=prod; var-file=envs/{dev,prod}.tfvars",
+			want:  TargetRule{RootModulePattern: "services/*", VarFiles: []string{"envs/{dev,prod}.tfvars"}, Workspaces: []string{"dev", "prod"}},
+		},
+		{input: "services/api", wantErr: true},
+		{input: "services/api:", wantErr: true},
+		{input: ":var-file=envs/*.tfvars", wantErr: true},
+		{input: "services/api:var-file=", wantErr: true},
+		{input: "services/api:backend=s3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseTargetRule(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTargetRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTargetRule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandTargets(t *testing.T) {
+	fsys := os.DirFS(filepath.Join("..", "..", "testdata", "terraform-targets"))
+
+	tests := []struct {
+		name  string
+		rules []TargetRule
+		want  map[string][]Target
+	}{
+		{
+			name:  "one target per var file",
+			rules: []TargetRule{{RootModulePattern: "services/api", VarFiles: []string{"envs/*.tfvars"}}},
+			want: map[string][]Target{
+				"services/api": {{VarFile: "envs/dev.tfvars"}, {VarFile: "envs/prod.tfvars"}},
+			},
+		},
+		{
+			name:  "workspaces pick up var files named after them",
+			rules: []TargetRule{{RootModulePattern: "services/web", VarFiles: []string{"envs/*.tfvars"}, Workspaces: []string{"dev", "prod", "staging"}}},
+			want: map[string][]Target{
+				"services/web": {
+					{VarFile: "envs/dev.tfvars", Workspace: "dev"},
+					{VarFile: "envs/prod.tfvars", Workspace: "prod"},
+					{Workspace: "staging"},
+				},
+			},
+		},
+		{
+			name: "first matching rule wins",
+			rules: []TargetRule{
+				{RootModulePattern: "services/api", Workspaces: []string{"default"}},
+				{RootModulePattern: "services/*", VarFiles: []string{"envs/prod.tfvars"}},
+			},
+			want: map[string][]Target{
+				"services/api": {{Workspace: "default"}},
+				"services/web": {{VarFile: "envs/prod.tfvars"}},
+			},
+		},
+		{
+			name:  "root modules without matching var files have no targets",
+			rules: []TargetRule{{RootModulePattern: "services/*", VarFiles: []string{"*.tfvars"}}},
+			want:  map[string][]Target{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandTargets(fsys, tt.rules)
+			if err != nil {
+				t.Fatalf("ExpandTargets() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandTargets() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
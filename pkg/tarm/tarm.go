@@ -0,0 +1,424 @@
+package tarm
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/kzmshx/tarm/pkg/git"
+)
+
+// Config holds the parameters for an analysis run.
+type Config struct {
+	// Root is the directory to search for Terraform files.
+	Root string
+
+	// PathBase is the directory the changed files, patterns, target rules, source rewrite
+	// paths and FailOnUnmapped directories are relative to. An empty value selects PathBaseRoot.
+	// Paths in the result are always relative to Root.
+	PathBase PathBase
+
+	// RootModulePatterns are glob patterns identifying root modules.
+	RootModulePatterns []string
+
+	// ExcludeModulePatterns are glob patterns for modules to exclude from root module detection.
+	ExcludeModulePatterns []string
+
+	// ChangedFiles are explicitly provided paths to treat as changed.
+	ChangedFiles []string
+
+	// IgnorePatterns are patterns in gitignore syntax for changed files to leave out of the analysis.
+	// Later patterns take precedence, and "!" re-includes files ignored by earlier ones.
+	IgnorePatterns []string
+
+	// IgnoreFile is a file of patterns in gitignore syntax, relative to the directory containing it,
+	// for changed files to leave out of the analysis. A missing file is not an error.
+	// IgnorePatterns take precedence over it.
+	IgnoreFile string
+
+	// TerraformIgnore leaves changed files matched by Terraform's default ignore rules or the
+	// .terraformignore file of the module they belong to, or of the root directory if none,
+	// out of the analysis. These take precedence over IgnorePatterns.
+	TerraformIgnore bool
+
+	// DetectChanges enables automatic changed file detection via the provider.
+	DetectChanges bool
+
+	// BaseRef is the base git ref for change detection.
+	BaseRef string
+
+	// HeadRef is the head git ref for change detection.
+	HeadRef string
+
+	// OutputFormat controls stdout output ("json" or "text").
+	OutputFormat string
+
+	// OpenTofu loads configuration as OpenTofu does, reading .tofu and .tofu.json files.
+	OpenTofu bool
+
+	// InheritedFilePatterns are glob patterns for files that affect every root module
+	// beneath their directory, e.g. "backend.hcl" or ".terraform-version".
+	InheritedFilePatterns []string
+
+	// GlobalTriggers mark root modules as affected whenever a matching file changes, regardless
+	// of their dependencies, e.g. for CI workflows or shared templates.
+	GlobalTriggers []GlobalTrigger
+
+	// SourceRewrites map remote module sources to local directories in the repository.
+	SourceRewrites []SourceRewrite
+
+	// OnParseError decides how modules whose configuration fails to parse are handled.
+	// An empty value selects ParseErrorSkip.
+	OnParseError ParseErrorPolicy
+
+	// Targets expand matching root modules into deployment targets per var file or workspace.
+	Targets []TargetRule
+
+	// FailOnCycles makes Run return a *CycleError if the dependency graph contains cycles.
+	FailOnCycles bool
+	// FailOnUnmapped makes Run return an *UnmappedError if changed files within one of these
+	// directories, relative to Root, map to no module. "." selects the whole root directory.
+	FailOnUnmapped []string
+	// Concurrency is the number of modules parsed at once; zero selects GOMAXPROCS.
+	Concurrency int
+	// CacheDir is the directory parsed modules are cached in; empty disables the cache.
+	CacheDir string
+	// DiagnosticHandler, if set, is called with every diagnostic as it is reported.
+	DiagnosticHandler func(Diagnostic)
+	// Strict makes Run return a *DiagnosticError if any diagnostic is reported.
+	Strict bool
+	// FailOn makes Run return a *DiagnosticError if a diagnostic with one of these codes is reported.
+	FailOn []DiagnosticCode
+}
+
+// Result holds the output of an analysis run.
+type Result struct {
+	AffectedModules []AffectedRootModule
+	Cycles          []Cycle
+	Diagnostics     []Diagnostic
+	// ChangedFiles are the changed paths, sorted by path, with what each maps to.
+	// Paths matched by an ignore rule have the status ChangeIgnored.
+	ChangedFiles []ChangedFile
+	// Refs are the refs compared, when changes are detected by a provider.
+	Refs    *Refs
+	Timings Timings
+}
+
+// UnmappedFiles returns the changed files that map to no module: those outside the root
+// directory, without a parent directory containing Terraform files, or not loaded in the current mode.
+func (r *Result) UnmappedFiles() []ChangedFile {
+	return unmappedFiles(r.ChangedFiles)
+}
+
+// IgnoredFiles returns the changed files matched by an ignore rule.
+func (r *Result) IgnoredFiles() []ChangedFile {
+	return ignoredFiles(r.ChangedFiles)
+}
+
+// Refs are the refs compared to detect changes and the commits they resolved to.
+// The SHAs are empty if the provider cannot resolve refs.
+type Refs struct {
+	Base    string `json:"base"`
+	Head    string `json:"head"`
+	BaseSHA string `json:"base_sha,omitempty"`
+	HeadSHA string `json:"head_sha,omitempty"`
+}
+
+// Timings are the durations of the stages of an analysis run.
+type Timings struct {
+	// ChangeDetection covers listing the changed files and resolving refs.
+	ChangeDetection time.Duration
+	// Analysis covers parsing the modules and building the dependency graph.
+	Analysis time.Duration
+	Total    time.Duration
+}
+
+// Run executes the analysis with the given config and change provider.
+// It returns the result without performing any I/O side effects (no file writes, no stdout).
+// Diagnostics are returned in the result and passed to Config.DiagnosticHandler as they are reported.
+func Run(ctx context.Context, cfg Config, changeProvider git.ChangedFilesProvider) (*Result, error) {
+	start := time.Now()
+	root := cfg.Root
+	if root == "" {
+		root = "."
+	}
+
+	if len(cfg.RootModulePatterns) == 0 {
+		return nil, fmt.Errorf("at least one root module pattern must be specified")
+	}
+	if _, err := ParseParseErrorPolicy(string(cfg.OnParseError)); err != nil {
+		return nil, err
+	}
+	cfg, _, err := rebaseConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	changedFiles, ignored, analyzerOpts, err := collectChanges(ctx, cfg, changeProvider)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := resolveRefs(ctx, cfg, changeProvider)
+	if err != nil {
+		return nil, err
+	}
+	var timings Timings
+	timings.ChangeDetection = time.Since(start)
+
+	targets, varFileChanges, changedFiles, err := targetChanges(root, cfg, changedFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	// Analyze
+	a := NewAnalyzer(root, analyzerOpts...)
+	analysisStart := time.Now()
+	if err := a.Analyze(ctx); err != nil {
+		return nil, fmt.Errorf("failed to analyze modules: %w", err)
+	}
+	timings.Analysis = time.Since(analysisStart)
+
+	// Detect circular dependencies
+	cycles := a.GetDependencyGraph().Cycles()
+	if cfg.FailOnCycles && len(cycles) > 0 {
+		return nil, &CycleError{Cycles: cycles}
+	}
+	for _, cycle := range cycles {
+		d := Diagnostic{
+			Code:    DiagDependencyCycle,
+			Message: fmt.Sprintf("circular dependency detected: %s", cycle),
+			Module:  cycle.Modules[0],
+		}
+		if len(cycle.Steps) > 0 {
+			d.File, d.Line = cycle.Steps[0].File, cycle.Steps[0].Line
+		}
+		a.diagnose(d)
+	}
+
+	matchRootModule, err := rootModuleMatcher(cfg, a)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get affected root modules
+	modules, err := a.AffectedRootModules(changedFiles, matchRootModule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get affected modules: %w", err)
+	}
+	modules = applyTargets(modules, targets, varFileChanges, matchRootModule)
+
+	mapped := a.ChangedFiles(changedFiles)
+	for _, change := range varFileChanges {
+		mapped = append(mapped, ChangedFile{
+			Path:    change.changedPath,
+			Status:  ChangeMapped,
+			Sources: []Cause{{Path: change.module, Reason: ReasonVarFile}},
+		})
+	}
+	mapped = append(mapped, ignored...)
+	slices.SortFunc(mapped, func(x, y ChangedFile) int { return cmp.Compare(x.Path, y.Path) })
+
+	if failing := failingDiagnostics(a.Diagnostics(), cfg.Strict, cfg.FailOn); len(failing) > 0 {
+		return nil, &DiagnosticError{Diagnostics: failing}
+	}
+	if unmapped := unmappedUnder(root, mapped, cfg.FailOnUnmapped); len(unmapped) > 0 {
+		return nil, &UnmappedError{Files: unmapped}
+	}
+	timings.Total = time.Since(start)
+
+	return &Result{
+		AffectedModules: modules,
+		Cycles:          cycles,
+		Diagnostics:     a.Diagnostics(),
+		ChangedFiles:    mapped,
+		Refs:            refs,
+		Timings:         timings,
+	}, nil
+}
+
+// resolveRefs returns the refs compared when change detection is enabled, resolved to commits
+// if the provider is a git.RefResolver.
+func resolveRefs(ctx context.Context, cfg Config, changeProvider git.ChangedFilesProvider) (*Refs, error) {
+	if !cfg.DetectChanges || changeProvider == nil {
+		return nil, nil
+	}
+	refs := &Refs{Base: cfg.BaseRef, Head: cfg.HeadRef}
+	if refs.Head == "" {
+		refs.Head = "HEAD"
+	}
+	if resolver, ok := changeProvider.(git.RefResolver); ok {
+		base, head, err := resolver.ResolveRefs(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve refs: %w", err)
+		}
+		refs.BaseSHA, refs.HeadSHA = base, head
+	}
+	return refs, nil
+}
+
+// collectChanges returns the changed paths given in the config and, when change detection is
+// enabled, those reported by the provider, along with the paths matched by the ignore rules,
+// which are left out, and the analyzer options for the analysis.
+// Renamed files contribute both their old and new paths.
+func collectChanges(ctx context.Context, cfg Config, changeProvider git.ChangedFilesProvider) ([]string, []ChangedFile, []AnalyzerOption, error) {
+	var changedFiles []string
+	analyzerOpts := analyzerOptions(cfg)
+
+	if cfg.DetectChanges && changeProvider != nil {
+		detected, err := git.ChangedPaths(ctx, changeProvider)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to detect changed files: %w", err)
+		}
+
+		// Paths relative to the top level of a repository are made relative to the root directory.
+		rebase := func(paths []string) []string { return paths }
+		if repo, ok := changeProvider.(git.RepositoryProvider); ok {
+			topLevel, err := repo.TopLevel(ctx)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("failed to find the repository: %w", err)
+			}
+			if topLevel != "" {
+				r, err := newRepoPaths(topLevel, cfg.Root)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				rebase = func(paths []string) []string {
+					rebased := make([]string, 0, len(paths))
+					for _, p := range paths {
+						rebased = append(rebased, r.path(p))
+					}
+					return rebased
+				}
+			}
+		}
+		changedFiles = append(changedFiles, rebase(detected)...)
+
+		if treeProvider, ok := changeProvider.(git.BaseTreeProvider); ok {
+			baseTree, err := treeProvider.BaseTree(ctx)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("failed to list base tree: %w", err)
+			}
+			analyzerOpts = append(analyzerOpts, WithBaseTree(rebase(baseTree)))
+		}
+	}
+
+	changedFiles = append(changedFiles, cfg.ChangedFiles...)
+	changedFiles, ignored, err := ignoreChanges(cfg, Unique(changedFiles))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return changedFiles, ignored, analyzerOpts, nil
+}
+
+// targetChanges expands the deployment targets and separates the changed var files of a target
+// from the other changed paths. Changes to a target's var file affect that target only,
+// so they are kept out of the module analysis.
+func targetChanges(root string, cfg Config, changedFiles []string) (map[string][]Target, []varFileChange, []string, error) {
+	targets, err := ExpandTargets(os.DirFS(root), cfg.Targets)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to expand targets: %w", err)
+	}
+	varFileChanges, changedFiles := splitVarFileChanges(root, changedFiles, targets)
+	return targets, varFileChanges, changedFiles, nil
+}
+
+// rootModuleMatcher returns the function identifying root modules: modules matching the root
+// module patterns and Terragrunt units, minus those matching the exclude patterns.
+func rootModuleMatcher(cfg Config, a *Analyzer) (func(string) bool, error) {
+	root := cfg.Root
+	if root == "" {
+		root = "."
+	}
+
+	// When ExcludeModulePatterns is specified, we resolve patterns against the
+	// filesystem to get a concrete set of root module paths, then use set lookup
+	// instead of pattern matching for root module detection.
+	var matchRootModule func(string) bool
+	if len(cfg.ExcludeModulePatterns) > 0 {
+		filtered, err := FilterPatterns(os.DirFS(root), cfg.RootModulePatterns, cfg.ExcludeModulePatterns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter root module patterns: %w", err)
+		}
+		rootModuleSet := make(map[string]bool, len(filtered))
+		for _, p := range filtered {
+			rootModuleSet[p] = true
+		}
+		// Deleted root modules are not found on the filesystem, so fall back to pattern matching for them.
+		matchRootModule = func(path string) bool {
+			if rootModuleSet[path] {
+				return true
+			}
+			if _, err := os.Stat(filepath.Join(root, path)); !os.IsNotExist(err) {
+				return false
+			}
+			return isRootModule(path, cfg.RootModulePatterns) && !isRootModule(path, cfg.ExcludeModulePatterns)
+		}
+	} else {
+		matchRootModule = func(path string) bool { return isRootModule(path, cfg.RootModulePatterns) }
+	}
+
+	// Terragrunt units are root modules unless explicitly excluded.
+	if units := a.TerragruntUnits(); len(units) > 0 {
+		unitSet := make(map[string]bool, len(units))
+		for _, unit := range units {
+			if !isRootModule(unit, cfg.ExcludeModulePatterns) {
+				unitSet[unit] = true
+			}
+		}
+		matchPattern := matchRootModule
+		matchRootModule = func(path string) bool { return matchPattern(path) || unitSet[path] }
+	}
+	return matchRootModule, nil
+}
+
+// Inventory analyzes the root directory and returns every module call found, with its parsed source.
+func Inventory(ctx context.Context, cfg Config) ([]ModuleCall, error) {
+	root := cfg.Root
+	if root == "" {
+		root = "."
+	}
+	cfg, _, err := rebaseConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	a := NewAnalyzer(root, analyzerOptions(cfg)...)
+	if err := a.Analyze(ctx); err != nil {
+		return nil, fmt.Errorf("failed to analyze modules: %w", err)
+	}
+	return a.GetDependencyGraph().ModuleCalls(), nil
+}
+
+// analyzerOptions returns the analyzer options derived from the config.
+func analyzerOptions(cfg Config) []AnalyzerOption {
+	var opts []AnalyzerOption
+	if cfg.OpenTofu {
+		opts = append(opts, WithOpenTofu())
+	}
+	if len(cfg.SourceRewrites) > 0 {
+		opts = append(opts, WithSourceRewrites(cfg.SourceRewrites))
+	}
+	if len(cfg.InheritedFilePatterns) > 0 {
+		opts = append(opts, WithInheritedFiles(cfg.InheritedFilePatterns))
+	}
+	if len(cfg.GlobalTriggers) > 0 {
+		opts = append(opts, WithGlobalTriggers(cfg.GlobalTriggers))
+	}
+	if cfg.OnParseError != "" {
+		opts = append(opts, WithParseErrorPolicy(cfg.OnParseError))
+	}
+	if cfg.Concurrency > 0 {
+		opts = append(opts, WithConcurrency(cfg.Concurrency))
+	}
+	if cfg.CacheDir != "" {
+		opts = append(opts, WithCache(cfg.CacheDir))
+	}
+	if cfg.DiagnosticHandler != nil {
+		opts = append(opts, WithDiagnosticHandler(cfg.DiagnosticHandler))
+	}
+	return opts
+}
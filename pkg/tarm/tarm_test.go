@@ -0,0 +1,418 @@
+package tarm
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kzmshx/tarm/pkg/git"
+)
+
+func TestRun(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform")
+
+	tests := []struct {
+		name        string
+		cfg         Config
+		detected    []string
+		wantModules []string
+		wantErr     bool
+	}{
+		{
+			name:        "explicit changed files",
+			cfg:         Config{Root: testRoot, RootModulePatterns: []string{"environments/*/*"}, ChangedFiles: []string{"modules/common/main.tf"}},
+			wantModules: []string{"environments/dev/api", "environments/dev/web", "environments/stg/api", "environments/stg/web"},
+		},
+		{
+			name:        "direct root module change",
+			cfg:         Config{Root: testRoot, RootModulePatterns: []string{"environments/*/*"}, ChangedFiles: []string{"environments/dev/api/main.tf"}},
+			wantModules: []string{"environments/dev/api"},
+		},
+		{
+			name: "no changed files",
+			cfg:  Config{Root: testRoot, RootModulePatterns: []string{"environments/*/*"}},
+		},
+		{
+			name:        "exclude module patterns",
+			cfg:         Config{Root: testRoot, RootModulePatterns: []string{"environments/*/*"}, ExcludeModulePatterns: []string{"environments/dev/*"}, ChangedFiles: []string{"modules/network/main.tf"}},
+			wantModules: []string{"environments/stg/api", "environments/stg/web", "environments/prod/app"},
+		},
+		{
+			name:    "missing patterns returns error",
+			cfg:     Config{Root: testRoot},
+			wantErr: true,
+		},
+		{
+			name:    "invalid parse error policy returns error",
+			cfg:     Config{Root: testRoot, RootModulePatterns: []string{"environments/*/*"}, OnParseError: "ignore"},
+			wantErr: true,
+		},
+		{
+			name:        "combines detected and explicit",
+			cfg:         Config{Root: testRoot, RootModulePatterns: []string{"environments/*/*"}, ChangedFiles: []string{"modules/auth/main.tf"}, DetectChanges: true},
+			detected:    []string{"modules/database/main.tf"},
+			wantModules: []string{"environments/dev/api", "environments/dev/web", "environments/stg/api", "environments/stg/web"},
+		},
+		{
+			name:        "detect changes with provider",
+			cfg:         Config{Root: testRoot, RootModulePatterns: []string{"environments/*/*"}, DetectChanges: true},
+			detected:    []string{"modules/network/main.tf"},
+			wantModules: []string{"environments/dev/api", "environments/dev/web", "environments/stg/api", "environments/stg/web", "environments/prod/app"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &git.StaticProvider{Files: tt.detected}
+
+			result, err := Run(t.Context(), tt.cfg, provider)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			var gotModules []string
+			for _, m := range result.AffectedModules {
+				gotModules = append(gotModules, m.Path)
+			}
+			sort.Strings(gotModules)
+			sort.Strings(tt.wantModules)
+
+			if len(gotModules) != len(tt.wantModules) {
+				t.Errorf("got %v, want %v", gotModules, tt.wantModules)
+				return
+			}
+			for i := range gotModules {
+				if gotModules[i] != tt.wantModules[i] {
+					t.Errorf("got %v, want %v", gotModules, tt.wantModules)
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestRun_ResultIsSorted(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform")
+	cfg := Config{
+		Root:               testRoot,
+		RootModulePatterns: []string{"environments/*/*"},
+		ChangedFiles:       []string{"modules/network/main.tf"},
+	}
+	result, err := Run(t.Context(), cfg, nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	for i := 1; i < len(result.AffectedModules); i++ {
+		if result.AffectedModules[i].Path < result.AffectedModules[i-1].Path {
+			t.Errorf("result is not sorted: %s comes after %s",
+				result.AffectedModules[i].Path, result.AffectedModules[i-1].Path)
+		}
+	}
+}
+
+func TestRun_WarningsOnParseErrors(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform-errors", "invalid-syntax")
+	cfg := Config{
+		Root:               testRoot,
+		RootModulePatterns: []string{"*"},
+		ChangedFiles:       []string{"main.tf"},
+	}
+	result, err := Run(t.Context(), cfg, nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(result.Diagnostics) == 0 {
+		t.Error("expected warnings for parse errors, got none")
+	}
+}
+
+func TestRun_TerragruntUnitsAreRootModules(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terragrunt")
+
+	tests := []struct {
+		name        string
+		cfg         Config
+		wantModules []string
+	}{
+		{
+			name:        "units are detected without matching patterns",
+			cfg:         Config{Root: testRoot, RootModulePatterns: []string{"environments/*/*"}, ChangedFiles: []string{"root.hcl"}},
+			wantModules: []string{"live/dev/vpc", "live/prod/app", "live/prod/vpc", "live/prod/worker"},
+		},
+		{
+			name:        "exclude patterns apply to units",
+			cfg:         Config{Root: testRoot, RootModulePatterns: []string{"environments/*/*"}, ExcludeModulePatterns: []string{"live/dev/*"}, ChangedFiles: []string{"modules/vpc/main.tf"}},
+			wantModules: []string{"live/prod/app", "live/prod/vpc", "live/prod/worker"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Run(t.Context(), tt.cfg, nil)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			var gotModules []string
+			for _, m := range result.AffectedModules {
+				gotModules = append(gotModules, m.Path)
+			}
+			if len(gotModules) != len(tt.wantModules) {
+				t.Fatalf("got %v, want %v", gotModules, tt.wantModules)
+			}
+			for i := range gotModules {
+				if gotModules[i] != tt.wantModules[i] {
+					t.Fatalf("got %v, want %v", gotModules, tt.wantModules)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_Targets(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform-targets")
+	rules := []TargetRule{
+		{RootModulePattern: "services/api", VarFiles: []string{"envs/*.tfvars"}},
+		{RootModulePattern: "services/web", VarFiles: []string{"envs/*.tfvars"}, Workspaces: []string{"dev", "prod", "staging"}},
+	}
+
+	tests := []struct {
+		name         string
+		changedFiles []string
+		want         map[string][]Target
+	}{
+		{
+			name:         "module change affects every target",
+			changedFiles: []string{"modules/app/main.tf"},
+			want: map[string][]Target{
+				"services/api":   {{VarFile: "envs/dev.tfvars"}, {VarFile: "envs/prod.tfvars"}},
+				"services/batch": nil,
+				"services/web":   {{VarFile: "envs/dev.tfvars", Workspace: "dev"}, {VarFile: "envs/prod.tfvars", Workspace: "prod"}, {Workspace: "staging"}},
+			},
+		},
+		{
+			name:         "var file change affects only its target",
+			changedFiles: []string{"services/api/envs/prod.tfvars", "services/web/envs/dev.tfvars"},
+			want: map[string][]Target{
+				"services/api": {{VarFile: "envs/prod.tfvars"}},
+				"services/web": {{VarFile: "envs/dev.tfvars", Workspace: "dev"}},
+			},
+		},
+		{
+			name:         "root module change together with a var file affects every target",
+			changedFiles: []string{"services/api/envs/dev.tfvars", "services/api/main.tf"},
+			want: map[string][]Target{
+				"services/api": {{VarFile: "envs/dev.tfvars"}, {VarFile: "envs/prod.tfvars"}},
+			},
+		},
+		{
+			name:         "var file of a root module without rules affects the root module",
+			changedFiles: []string{"services/batch/envs/default.tfvars"},
+			want: map[string][]Target{
+				"services/batch": nil,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Root:               testRoot,
+				RootModulePatterns: []string{"services/*"},
+				ChangedFiles:       tt.changedFiles,
+				Targets:            rules,
+			}
+			result, err := Run(t.Context(), cfg, nil)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			got := make(map[string][]Target)
+			for _, m := range result.AffectedModules {
+				got[m.Path] = m.Targets
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("targets = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_Cycles(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform-cycles")
+	cfg := Config{
+		Root:               testRoot,
+		RootModulePatterns: []string{"environments/*"},
+		ChangedFiles:       []string{"modules/b/main.tf"},
+	}
+
+	result, err := Run(t.Context(), cfg, nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	var got [][]string
+	for _, c := range result.Cycles {
+		got = append(got, c.Modules)
+	}
+	want := [][]string{{"modules/a", "modules/b"}, {"modules/x", "modules/y", "modules/z"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cycles = %v, want %v", got, want)
+	}
+	if len(result.AffectedModules) != 1 || result.AffectedModules[0].Path != "environments/app" {
+		t.Errorf("affected modules = %+v, want environments/app", result.AffectedModules)
+	}
+
+	cfg.FailOnCycles = true
+	_, err = Run(t.Context(), cfg, nil)
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("Run() error = %v, want *CycleError", err)
+	}
+	if len(cycleErr.Cycles) != 2 {
+		t.Errorf("CycleError.Cycles = %v, want 2 cycles", cycleErr.Cycles)
+	}
+}
+
+type fakeDiffProvider struct {
+	changes  []git.FileChange
+	baseTree []string
+}
+
+func (p *fakeDiffProvider) ChangedFiles(context.Context) ([]string, error) {
+	var files []string
+	for _, c := range p.changes {
+		files = append(files, c.Path)
+	}
+	return files, nil
+}
+
+func (p *fakeDiffProvider) Changes(context.Context) ([]git.FileChange, error) { return p.changes, nil }
+
+func (p *fakeDiffProvider) BaseTree(context.Context) ([]string, error) { return p.baseTree, nil }
+
+func TestRun_DeletedAndRenamedFiles(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform")
+
+	tests := []struct {
+		name        string
+		cfg         Config
+		provider    *fakeDiffProvider
+		wantModules []string
+		wantDeleted []string
+	}{
+		{
+			name:        "renamed file affects old and new modules",
+			cfg:         Config{Root: testRoot, RootModulePatterns: []string{"environments/*/*"}, DetectChanges: true},
+			provider:    &fakeDiffProvider{changes: []git.FileChange{{Status: git.StatusRenamed, OldPath: "modules/auth/old.tf", Path: "modules/database/main.tf"}}},
+			wantModules: []string{"environments/dev/api", "environments/dev/web", "environments/stg/api", "environments/stg/web"},
+		},
+		{
+			name: "deleted root module is reported with exclude patterns",
+			cfg:  Config{Root: testRoot, RootModulePatterns: []string{"environments/*/*"}, ExcludeModulePatterns: []string{"environments/stg/*"}, DetectChanges: true},
+			provider: &fakeDiffProvider{
+				changes:  []git.FileChange{{Status: git.StatusDeleted, Path: "environments/dev/legacy/main.tf"}},
+				baseTree: []string{"environments/dev/legacy/main.tf"},
+			},
+			wantModules: []string{"environments/dev/legacy"},
+			wantDeleted: []string{"environments/dev/legacy"},
+		},
+		{
+			name: "deleted excluded root module is not reported",
+			cfg:  Config{Root: testRoot, RootModulePatterns: []string{"environments/*/*"}, ExcludeModulePatterns: []string{"environments/dev/*"}, DetectChanges: true},
+			provider: &fakeDiffProvider{
+				changes:  []git.FileChange{{Status: git.StatusDeleted, Path: "environments/dev/legacy/main.tf"}},
+				baseTree: []string{"environments/dev/legacy/main.tf"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Run(t.Context(), tt.cfg, tt.provider)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			var gotModules, gotDeleted []string
+			for _, m := range result.AffectedModules {
+				gotModules = append(gotModules, m.Path)
+				if m.Deleted {
+					gotDeleted = append(gotDeleted, m.Path)
+				}
+			}
+			sort.Strings(tt.wantModules)
+			if strings.Join(gotModules, ",") != strings.Join(tt.wantModules, ",") {
+				t.Errorf("got %v, want %v", gotModules, tt.wantModules)
+			}
+			if strings.Join(gotDeleted, ",") != strings.Join(tt.wantDeleted, ",") {
+				t.Errorf("got deleted %v, want %v", gotDeleted, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestRun_UnmappedFiles(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform")
+
+	tests := []struct {
+		name           string
+		failOnUnmapped []string
+		wantFailing    []string
+	}{
+		{name: "not failing by default"},
+		{name: "unmapped files under a directory", failOnUnmapped: []string{"scripts"}, wantFailing: []string{"scripts/deploy.sh"}},
+		{name: "no unmapped files under the directories", failOnUnmapped: []string{"modules", "environments"}},
+		{name: "whole root directory", failOnUnmapped: []string{"."}, wantFailing: []string{"README.md", "scripts/deploy.sh"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Root:               testRoot,
+				RootModulePatterns: []string{"environments/*/*"},
+				ChangedFiles:       []string{"scripts/deploy.sh", "modules/database/main.tf", "README.md", "../outside.tf"},
+				FailOnUnmapped:     tt.failOnUnmapped,
+			}
+
+			result, err := Run(t.Context(), cfg, nil)
+			if tt.wantFailing != nil {
+				var unmappedErr *UnmappedError
+				if !errors.As(err, &unmappedErr) {
+					t.Fatalf("Run() error = %v, want *UnmappedError", err)
+				}
+				var got []string
+				for _, f := range unmappedErr.Files {
+					got = append(got, f.Path)
+				}
+				if !reflect.DeepEqual(got, tt.wantFailing) {
+					t.Errorf("failing files = %v, want %v", got, tt.wantFailing)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			want := []ChangedFile{
+				{Path: "../outside.tf", Status: ChangeOutsideRoot},
+				{Path: "README.md", Status: ChangeNoModule},
+				{Path: "scripts/deploy.sh", Status: ChangeNoModule},
+			}
+			if got := result.UnmappedFiles(); !reflect.DeepEqual(got, want) {
+				t.Errorf("UnmappedFiles() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
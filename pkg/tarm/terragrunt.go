@@ -0,0 +1,169 @@
+package tarm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// TerragruntConfigFile is the name of the file that marks a directory as a Terragrunt unit.
+const TerragruntConfigFile = "terragrunt.hcl"
+
+// TerragruntUnit holds the local paths a Terragrunt unit depends on. All paths are absolute.
+type TerragruntUnit struct {
+	// Source is the local module directory from the terraform block's source, if any.
+	Source string
+	// RawSource is the terraform block's source after evaluating functions, local or remote.
+	RawSource string
+	// Dependencies are the unit directories listed in dependency and dependencies blocks.
+	Dependencies []string
+	// Inputs are configuration files read via include blocks and read_terragrunt_config().
+	Inputs []string
+}
+
+// LoadTerragruntUnit parses the terragrunt.hcl in dir. Expressions that cannot be
+// resolved statically, e.g. those referring to locals or dependency outputs, are ignored.
+func LoadTerragruntUnit(dir string) (*TerragruntUnit, error) {
+	filename := filepath.Join(dir, TerragruntConfigFile)
+	file, diags := hclparse.NewParser().ParseHCLFile(filename)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported syntax", filename)
+	}
+
+	ctx := terragruntEvalContext(dir)
+	resolve := func(expr hclsyntax.Expression) (string, bool) {
+		path, ok := evalString(expr, ctx)
+		if !ok || path == "" {
+			return "", false
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		return filepath.Clean(path), true
+	}
+
+	unit := &TerragruntUnit{}
+	for _, block := range body.Blocks {
+		switch block.Type {
+		case "terraform":
+			if attr, ok := block.Body.Attributes["source"]; ok {
+				source, ok := evalString(attr.Expr, ctx)
+				if ok {
+					unit.RawSource = source
+				}
+				if ok && !ParseModuleSource(source).IsRemote() {
+					if !filepath.IsAbs(source) {
+						source = filepath.Join(dir, source)
+					}
+					// The double slash separating the module root from the subdirectory
+					// collapses into a plain path to the module directory.
+					unit.Source = filepath.Clean(source)
+				}
+			}
+		case "dependency":
+			if attr, ok := block.Body.Attributes["config_path"]; ok {
+				if path, ok := resolve(attr.Expr); ok {
+					unit.Dependencies = append(unit.Dependencies, path)
+				}
+			}
+		case "dependencies":
+			if attr, ok := block.Body.Attributes["paths"]; ok {
+				unit.Dependencies = append(unit.Dependencies, evalPathList(dir, attr.Expr, ctx)...)
+			}
+		case "include":
+			if attr, ok := block.Body.Attributes["path"]; ok {
+				if path, ok := resolve(attr.Expr); ok {
+					unit.Inputs = append(unit.Inputs, path)
+				}
+			}
+		}
+	}
+
+	hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+		call, ok := node.(*hclsyntax.FunctionCallExpr)
+		if !ok || call.Name != "read_terragrunt_config" || len(call.Args) == 0 {
+			return nil
+		}
+		if path, ok := resolve(call.Args[0]); ok {
+			unit.Inputs = append(unit.Inputs, path)
+		}
+		return nil
+	})
+
+	unit.Dependencies = Unique(unit.Dependencies)
+	unit.Inputs = Unique(unit.Inputs)
+	return unit, nil
+}
+
+func evalPathList(dir string, expr hclsyntax.Expression, ctx *hcl.EvalContext) []string {
+	v, diags := expr.Value(ctx)
+	if diags.HasErrors() || !v.IsWhollyKnown() || v.IsNull() || !v.CanIterateElements() {
+		return nil
+	}
+
+	var paths []string
+	for it := v.ElementIterator(); it.Next(); {
+		_, elem := it.Element()
+		if elem.IsNull() || elem.Type() != cty.String {
+			continue
+		}
+		path := elem.AsString()
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		paths = append(paths, filepath.Clean(path))
+	}
+	return paths
+}
+
+// terragruntEvalContext provides the Terragrunt built-in functions needed to resolve paths.
+func terragruntEvalContext(dir string) *hcl.EvalContext {
+	dirFunc := function.New(&function.Spec{
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			return cty.StringVal(dir), nil
+		},
+	})
+
+	findInParentFolders := function.New(&function.Spec{
+		VarParam: &function.Parameter{Name: "args", Type: cty.String},
+		Type:     function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			name := TerragruntConfigFile
+			if len(args) > 0 {
+				name = args[0].AsString()
+			}
+			for current := filepath.Dir(dir); ; current = filepath.Dir(current) {
+				candidate := filepath.Join(current, name)
+				if _, err := os.Stat(candidate); err == nil {
+					return cty.StringVal(candidate), nil
+				}
+				if current == filepath.Dir(current) {
+					break
+				}
+			}
+			if len(args) > 1 {
+				return args[1], nil
+			}
+			return cty.NilVal, fmt.Errorf("could not find %s in any parent folder of %s", name, dir)
+		},
+	})
+
+	return &hcl.EvalContext{
+		Functions: map[string]function.Function{
+			"find_in_parent_folders":      findInParentFolders,
+			"get_terragrunt_dir":          dirFunc,
+			"get_original_terragrunt_dir": dirFunc,
+		},
+	}
+}
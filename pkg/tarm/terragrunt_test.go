@@ -0,0 +1,62 @@
+package tarm
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadTerragruntUnit(t *testing.T) {
+	testRoot, _ := filepath.Abs(filepath.Join("..", "..", "testdata", "terragrunt"))
+
+	tests := []struct {
+		name       string
+		dir        string
+		wantSource string
+		wantDeps   []string
+		wantInputs []string
+	}{
+		{name: "relative source with subdir and read_terragrunt_config", dir: "live/prod/vpc", wantSource: "modules/vpc", wantInputs: []string{"root.hcl", "live/prod/env.hcl"}},
+		{name: "get_terragrunt_dir source and dependency block", dir: "live/prod/app", wantSource: "modules/app", wantDeps: []string{"live/prod/vpc"}, wantInputs: []string{"root.hcl"}},
+		{name: "remote source and dependencies block", dir: "live/prod/worker", wantDeps: []string{"live/prod/app"}, wantInputs: []string{"root.hcl"}},
+	}
+
+	rel := func(path string) string {
+		if path == "" {
+			return ""
+		}
+		r, err := filepath.Rel(testRoot, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unit, err := LoadTerragruntUnit(filepath.Join(testRoot, tt.dir))
+			if err != nil {
+				t.Fatalf("LoadTerragruntUnit() error = %v", err)
+			}
+
+			if got := rel(unit.Source); got != tt.wantSource {
+				t.Errorf("Source = %q, want %q", got, tt.wantSource)
+			}
+			if len(unit.Dependencies) != len(tt.wantDeps) {
+				t.Fatalf("Dependencies = %v, want %v", unit.Dependencies, tt.wantDeps)
+			}
+			for i, dep := range unit.Dependencies {
+				if got := rel(dep); got != tt.wantDeps[i] {
+					t.Errorf("Dependencies[%d] = %q, want %q", i, got, tt.wantDeps[i])
+				}
+			}
+			if len(unit.Inputs) != len(tt.wantInputs) {
+				t.Fatalf("Inputs = %v, want %v", unit.Inputs, tt.wantInputs)
+			}
+			for i, input := range unit.Inputs {
+				if got := rel(input); got != tt.wantInputs[i] {
+					t.Errorf("Inputs[%d] = %q, want %q", i, got, tt.wantInputs[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package tarm
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// GlobalTrigger marks root modules as affected whenever a matching file changes, regardless of
+// the dependency graph, e.g. for CI workflows or templates the configuration is generated from.
+type GlobalTrigger struct {
+	// Pattern is a glob pattern, relative to the root directory, for the files that trigger.
+	// A pattern without a "/" matches file names at any depth, and files outside the root
+	// directory are matched with a leading "../".
+	Pattern string
+	// RootModulePatterns are glob patterns selecting the root modules affected. If empty,
+	// every root module is affected.
+	RootModulePatterns []string
+}
+
+// ParseGlobalTrigger parses a trigger of the form "<pattern>[=<root-module-pattern>;<root-module-pattern>...]",
+// e.g. "../.github/workflows/terraform.yml" or "templates/providers.tf.tmpl=environments/prod/*".
+func ParseGlobalTrigger(s string) (GlobalTrigger, error) {
+	pattern, roots, hasRoots := strings.Cut(s, "=")
+	trigger := GlobalTrigger{Pattern: strings.TrimSpace(pattern)}
+	if trigger.Pattern == "" {
+		return GlobalTrigger{}, fmt.Errorf("invalid global trigger %q: expected <pattern>[=<root-module-pattern>]", s)
+	}
+	if !doublestar.ValidatePattern(trigger.Pattern) {
+		return GlobalTrigger{}, fmt.Errorf("invalid global trigger %q: malformed pattern %q", s, trigger.Pattern)
+	}
+	for _, root := range strings.Split(roots, ";") {
+		root = strings.TrimSpace(root)
+		if root == "" {
+			continue
+		}
+		if !doublestar.ValidatePattern(root) {
+			return GlobalTrigger{}, fmt.Errorf("invalid global trigger %q: malformed root module pattern %q", s, root)
+		}
+		trigger.RootModulePatterns = append(trigger.RootModulePatterns, root)
+	}
+	if hasRoots && len(trigger.RootModulePatterns) == 0 {
+		return GlobalTrigger{}, fmt.Errorf("invalid global trigger %q: expected a root module pattern after \"=\"", s)
+	}
+	return trigger, nil
+}
+
+// Matches reports whether the changed path, relative to the root directory, triggers.
+func (t GlobalTrigger) Matches(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	name := relPath
+	if !strings.Contains(t.Pattern, "/") {
+		name = path.Base(relPath)
+	}
+	matched, err := doublestar.Match(t.Pattern, name)
+	return err == nil && matched
+}
+
+// Affects reports whether the trigger affects the root module.
+func (t GlobalTrigger) Affects(module string) bool {
+	return len(t.RootModulePatterns) == 0 || isRootModule(module, t.RootModulePatterns)
+}
+
+// globalTriggerSource returns the source for a changed path matching a global trigger.
+func (a *Analyzer) globalTriggerSource(changePath string) (changeSource, bool) {
+	if len(a.globalTriggers) == 0 {
+		return changeSource{}, false
+	}
+	if !filepath.IsAbs(changePath) {
+		changePath = filepath.Join(a.root, changePath)
+	}
+	relPath, err := filepath.Rel(a.root, changePath)
+	if err != nil {
+		return changeSource{}, false
+	}
+	for _, t := range a.globalTriggers {
+		if t.Matches(relPath) {
+			return changeSource{path: relPath, reason: ReasonGlobalTrigger}, true
+		}
+	}
+	return changeSource{}, false
+}
+
+// globallyTriggers reports whether a global trigger matching the changed path, relative to the
+// root directory, affects the module.
+func (a *Analyzer) globallyTriggers(relPath, module string) bool {
+	for _, t := range a.globalTriggers {
+		if t.Matches(relPath) && t.Affects(module) {
+			return true
+		}
+	}
+	return false
+}
+
+// rebaseGlobalTriggers converts global triggers relative to the top level of a repository into
+// ones relative to the root directory. Patterns for files outside the root directory get a
+// leading "../" for each directory between the root directory and the top level.
+func rebaseGlobalTriggers(r *repoPaths, triggers []GlobalTrigger) ([]GlobalTrigger, error) {
+	up := strings.TrimSuffix(strings.Repeat("../", strings.Count(r.prefix, "/")+1), "/")
+	rebased := make([]GlobalTrigger, 0, len(triggers))
+	for _, t := range triggers {
+		if strings.Contains(t.Pattern, "/") && r.prefix != "." {
+			if rp, ok := r.pattern(t.Pattern); ok {
+				t.Pattern = rp
+			} else {
+				t.Pattern = up + "/" + strings.TrimPrefix(t.Pattern, "/")
+			}
+		}
+		patterns := make([]string, 0, len(t.RootModulePatterns))
+		for _, p := range t.RootModulePatterns {
+			rp, ok := r.pattern(p)
+			if !ok {
+				return nil, fmt.Errorf("global trigger root module pattern %q matches nothing within the root directory %s", p, r.prefix)
+			}
+			patterns = append(patterns, rp)
+		}
+		t.RootModulePatterns = patterns
+		rebased = append(rebased, t)
+	}
+	return rebased, nil
+}
@@ -0,0 +1,163 @@
+package tarm
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseGlobalTrigger(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    GlobalTrigger
+		wantErr bool
+	}{
+		{input: "../.github/workflows/terraform.yml", want: GlobalTrigger{Pattern: "../.github/workflows/terraform.yml"}},
+		{input: "providers.tf.tmpl=environments/prod/*", want: GlobalTrigger{Pattern: "providers.tf.tmpl", RootModulePatterns: []string{"environments/prod/*"}}},
+		{input: "templates/*.tmpl=environments/dev/*; environments/stg/*", want: GlobalTrigger{Pattern: "templates/*.tmpl", RootModulePatterns: []string{"environments/dev/*", "environments/stg/*"}}},
+		{input: "=environments/*/*", wantErr: true},
+		{input: "versions.tf.tmpl=", wantErr: true},
+		{input: "templates/[*.tmpl", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseGlobalTrigger(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGlobalTrigger() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGlobalTrigger() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebaseGlobalTriggers(t *testing.T) {
+	r := &repoPaths{topLevel: "/repo", root: "/repo/infra/terraform", prefix: "infra/terraform"}
+
+	got, err := rebaseGlobalTriggers(r, []GlobalTrigger{
+		{Pattern: ".github/workflows/terraform.yml"},
+		{Pattern: "infra/terraform/templates/*.tmpl", RootModulePatterns: []string{"infra/terraform/environments/prod/*"}},
+		{Pattern: "versions.tf.tmpl"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []GlobalTrigger{
+		{Pattern: "../../.github/workflows/terraform.yml", RootModulePatterns: []string{}},
+		{Pattern: "templates/*.tmpl", RootModulePatterns: []string{"environments/prod/*"}},
+		{Pattern: "versions.tf.tmpl", RootModulePatterns: []string{}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rebaseGlobalTriggers() = %+v, want %+v", got, want)
+	}
+
+	if _, err := rebaseGlobalTriggers(r, []GlobalTrigger{{Pattern: "*.yml", RootModulePatterns: []string{"other/*"}}}); err == nil {
+		t.Error("rebaseGlobalTriggers() error = nil, want an error for a root module pattern outside the root directory")
+	}
+}
+
+func TestRun_GlobalTriggers(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform")
+	all := []string{
+		"environments/dev/api",
+		"environments/dev/web",
+		"environments/prod/app",
+		"environments/standalone/simple",
+		"environments/stg/api",
+		"environments/stg/web",
+	}
+
+	tests := []struct {
+		name         string
+		triggers     []GlobalTrigger
+		changedFiles []string
+		want         []string
+	}{
+		{
+			name:         "file outside the root directory",
+			triggers:     []GlobalTrigger{{Pattern: "../.github/workflows/*.yml"}},
+			changedFiles: []string{"../.github/workflows/terraform.yml"},
+			want:         all,
+		},
+		{
+			name:         "file name at any depth",
+			triggers:     []GlobalTrigger{{Pattern: "providers.tf.tmpl"}},
+			changedFiles: []string{"templates/aws/providers.tf.tmpl"},
+			want:         all,
+		},
+		{
+			name:         "subset of root modules",
+			triggers:     []GlobalTrigger{{Pattern: "templates/**", RootModulePatterns: []string{"environments/stg/*", "environments/prod/*"}}},
+			changedFiles: []string{"templates/versions.tf.tmpl"},
+			want:         []string{"environments/prod/app", "environments/stg/api", "environments/stg/web"},
+		},
+		{
+			name:         "no matching file",
+			triggers:     []GlobalTrigger{{Pattern: "../.github/workflows/*.yml"}},
+			changedFiles: []string{"../.github/CODEOWNERS"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Root:               testRoot,
+				RootModulePatterns: []string{"environments/*/*"},
+				ChangedFiles:       tt.changedFiles,
+				GlobalTriggers:     tt.triggers,
+			}
+			result, err := Run(t.Context(), cfg, nil)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			var got []string
+			for _, m := range result.AffectedModules {
+				got = append(got, m.Path)
+				if reason := m.ReasonFor(tt.changedFiles[0]); reason != ReasonGlobalTrigger {
+					t.Errorf("%s: ReasonFor(%s) = %s, want %s", m.Path, tt.changedFiles[0], reason, ReasonGlobalTrigger)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("affected modules = %v, want %v", got, tt.want)
+			}
+			if tt.want != nil && len(result.UnmappedFiles()) > 0 {
+				t.Errorf("UnmappedFiles() = %+v, want none", result.UnmappedFiles())
+			}
+		})
+	}
+}
+
+func TestWhy_GlobalTrigger(t *testing.T) {
+	cfg := Config{
+		Root:               filepath.Join("..", "..", "testdata", "terraform"),
+		RootModulePatterns: []string{"environments/*/*"},
+		ChangedFiles:       []string{"templates/versions.tf.tmpl"},
+		GlobalTriggers:     []GlobalTrigger{{Pattern: "templates/*.tmpl", RootModulePatterns: []string{"environments/prod/*"}}},
+	}
+
+	tests := []struct {
+		root string
+		want WhyStatus
+	}{
+		{root: "environments/prod/app", want: WhyAffected},
+		{root: "environments/dev/api", want: WhyNoDependencyPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.root, func(t *testing.T) {
+			e, err := Why(t.Context(), cfg, nil, tt.root)
+			if err != nil {
+				t.Fatalf("Why() error = %v", err)
+			}
+			if e.Status != tt.want {
+				t.Errorf("Status = %s, want %s", e.Status, tt.want)
+			}
+			want := []Cause{{Path: "templates/versions.tf.tmpl", Reason: ReasonGlobalTrigger}}
+			if got := e.Paths[0].Sources; !reflect.DeepEqual(got, want) {
+				t.Errorf("Sources = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
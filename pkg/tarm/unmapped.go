@@ -0,0 +1,64 @@
+package tarm
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// UnmappedError is returned when changed files under the directories in Config.FailOnUnmapped
+// map to no module.
+type UnmappedError struct {
+	Files []ChangedFile
+}
+
+func (e *UnmappedError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "found %d changed file(s) that map to no module:", len(e.Files))
+	for _, f := range e.Files {
+		fmt.Fprintf(&sb, "\n  %s (%s)", f.Path, f.Status)
+	}
+	return sb.String()
+}
+
+// unmappedFiles returns the changed files that map to no module, other than ignored ones.
+func unmappedFiles(files []ChangedFile) []ChangedFile {
+	var unmapped []ChangedFile
+	for _, f := range files {
+		if !f.Mapped() && f.Status != ChangeIgnored {
+			unmapped = append(unmapped, f)
+		}
+	}
+	return unmapped
+}
+
+// unmappedUnder returns the unmapped files within one of dirs, relative to root.
+func unmappedUnder(root string, files []ChangedFile, dirs []string) []ChangedFile {
+	if len(dirs) == 0 {
+		return nil
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		absRoot = root
+	}
+
+	var under []ChangedFile
+	for _, f := range unmappedFiles(files) {
+		if f.Status == ChangeOutsideRoot {
+			continue
+		}
+		rel := f.Path
+		if filepath.IsAbs(rel) {
+			if rel, err = filepath.Rel(absRoot, rel); err != nil {
+				continue
+			}
+		}
+		for _, dir := range dirs {
+			if IsWithinDirectory(rel, dir) {
+				under = append(under, f)
+				break
+			}
+		}
+	}
+	return under
+}
@@ -0,0 +1,183 @@
+package tarm
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/kzmshx/tarm/pkg/git"
+)
+
+// WhyStatus is the outcome of explaining whether a changed path affects a root module.
+type WhyStatus string
+
+const (
+	// WhyAffected means the root module is affected.
+	WhyAffected WhyStatus = "affected"
+	// WhyOutsideRoot means the changed path is outside the root directory.
+	WhyOutsideRoot WhyStatus = "outside_root"
+	// WhyNoModule means the changed path has no parent directory containing Terraform files.
+	WhyNoModule WhyStatus = "no_module"
+	// WhyShadowed means the changed path is a configuration file not loaded in the current mode.
+	WhyShadowed WhyStatus = "shadowed"
+	// WhyIgnored means the changed path is matched by an ignore rule.
+	WhyIgnored WhyStatus = "ignored"
+	// WhyExcluded means the root module matches an exclude module pattern.
+	WhyExcluded WhyStatus = "excluded"
+	// WhyPatternMismatch means the module matches no root module pattern and is not a Terragrunt unit.
+	WhyPatternMismatch WhyStatus = "pattern_mismatch"
+	// WhyNoDependencyPath means no dependency path leads from the root module to the changed path.
+	WhyNoDependencyPath WhyStatus = "no_dependency_path"
+)
+
+// Explanation tells why a root module is or is not affected by the changed paths.
+type Explanation struct {
+	Root string `json:"root"`
+	// Status is WhyAffected if any changed path affects the root module. Otherwise it is
+	// WhyExcluded or WhyPatternMismatch if the module is not a root module, or WhyNoDependencyPath.
+	Status WhyStatus         `json:"status"`
+	Paths  []PathExplanation `json:"paths"`
+	// Diagnostics are the conditions found while analyzing the modules.
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// Affected reports whether the root module is affected.
+func (e Explanation) Affected() bool {
+	return e.Status == WhyAffected
+}
+
+// PathExplanation tells how a changed path reaches, or fails to reach, the root module.
+type PathExplanation struct {
+	Path   string    `json:"path"`
+	Status WhyStatus `json:"status"`
+	// Sources are the modules, referenced files or inherited files the path maps to.
+	Sources []Cause `json:"sources,omitempty"`
+	// Chains are every shortest chain from the root module to the sources.
+	Chains []Chain `json:"chains,omitempty"`
+	// IgnoredBy is the ignore rule matching the path when the status is WhyIgnored.
+	IgnoredBy string `json:"ignored_by,omitempty"`
+}
+
+// Why explains whether the module at rootModule, relative to the root directory, is affected by
+// the changed paths in the config and, when change detection is enabled, those reported by the provider.
+// The status of every changed path is computed regardless of whether the module is a root module.
+func Why(ctx context.Context, cfg Config, changeProvider git.ChangedFilesProvider, rootModule string) (*Explanation, error) {
+	root := cfg.Root
+	if root == "" {
+		root = "."
+	}
+	if _, err := ParseParseErrorPolicy(string(cfg.OnParseError)); err != nil {
+		return nil, err
+	}
+	cfg, repo, err := rebaseConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if repo != nil && !filepath.IsAbs(rootModule) {
+		rootModule = repo.path(rootModule)
+	}
+
+	changedFiles, ignored, analyzerOpts, err := collectChanges(ctx, cfg, changeProvider)
+	if err != nil {
+		return nil, err
+	}
+	_, varFileChanges, changedFiles, err := targetChanges(root, cfg, changedFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	a := NewAnalyzer(root, analyzerOpts...)
+	if err := a.Analyze(ctx); err != nil {
+		return nil, fmt.Errorf("failed to analyze modules: %w", err)
+	}
+	matchRootModule, err := rootModuleMatcher(cfg, a)
+	if err != nil {
+		return nil, err
+	}
+
+	rootModule = filepath.Clean(rootModule)
+	if filepath.IsAbs(rootModule) {
+		if rel, err := filepath.Rel(a.root, rootModule); err == nil {
+			rootModule = rel
+		}
+	}
+
+	// Changed paths are mapped as Run maps them: var files of a target affect their root module
+	// only, and modules that failed to parse are changed when the policy treats them as affected.
+	e := &Explanation{Root: rootModule, Status: WhyNoDependencyPath}
+	for _, changePath := range changedFiles {
+		e.Paths = append(e.Paths, a.explainChange(changePath, rootModule))
+	}
+	for _, change := range varFileChanges {
+		source := changeSource{path: change.module, reason: ReasonVarFile}
+		e.Paths = append(e.Paths, a.explainSources(PathExplanation{Path: change.changedPath}, []changeSource{source}, rootModule))
+	}
+	for _, source := range a.parseErrorSources() {
+		e.Paths = append(e.Paths, a.explainSources(PathExplanation{Path: source.path}, []changeSource{source}, rootModule))
+	}
+	for _, f := range ignored {
+		e.Paths = append(e.Paths, PathExplanation{Path: f.Path, Status: WhyIgnored, IgnoredBy: f.IgnoredBy})
+	}
+	slices.SortStableFunc(e.Paths, func(x, y PathExplanation) int { return cmp.Compare(x.Path, y.Path) })
+	reachable := slices.ContainsFunc(e.Paths, func(p PathExplanation) bool { return p.Status == WhyAffected })
+
+	switch {
+	case !matchRootModule(rootModule) && isRootModule(rootModule, cfg.ExcludeModulePatterns):
+		e.Status = WhyExcluded
+	case !matchRootModule(rootModule):
+		e.Status = WhyPatternMismatch
+	case reachable:
+		e.Status = WhyAffected
+	}
+	e.Diagnostics = a.Diagnostics()
+	return e, nil
+}
+
+// explainChange maps a changed path onto the dependency graph and collects every shortest
+// chain from the module to what the path maps to.
+func (a *Analyzer) explainChange(changePath, module string) PathExplanation {
+	p := PathExplanation{Path: changePath}
+
+	sources, status := a.mapChange(changePath)
+	switch status {
+	case ChangeOutsideRoot:
+		p.Status = WhyOutsideRoot
+		return p
+	case ChangeNoModule:
+		p.Status = WhyNoModule
+		return p
+	case ChangeShadowed:
+		p.Status = WhyShadowed
+		return p
+	}
+	return a.explainSources(p, sources, module)
+}
+
+// explainSources records the sources a changed path maps to and every shortest chain from
+// the module to them. A var file of a target reaches its root module only.
+func (a *Analyzer) explainSources(p PathExplanation, sources []changeSource, module string) PathExplanation {
+	for _, source := range sources {
+		p.Sources = append(p.Sources, Cause{Path: source.path, Reason: source.reason})
+		switch source.reason {
+		case ReasonInherited, ReasonGlobalTrigger:
+			if a.reachesDirectly(source, module) {
+				p.Chains = append(p.Chains, Chain{Cause: source.path})
+			}
+			continue
+		case ReasonVarFile:
+			if source.path == module {
+				p.Chains = append(p.Chains, Chain{Cause: source.path})
+			}
+			continue
+		}
+		p.Chains = append(p.Chains, a.graph.Chains(module, source.path)...)
+	}
+
+	p.Status = WhyNoDependencyPath
+	if len(p.Chains) > 0 {
+		p.Status = WhyAffected
+	}
+	return p
+}
@@ -0,0 +1,174 @@
+package tarm
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWhy(t *testing.T) {
+	testRoot := filepath.Join("..", "..", "testdata", "terraform")
+
+	tests := []struct {
+		name         string
+		rootModule   string
+		changedFiles []string
+		exclude      []string
+		wantStatus   WhyStatus
+		wantPaths    []WhyStatus
+		wantChains   []string
+	}{
+		{
+			name:         "every shortest chain is reported",
+			rootModule:   "environments/stg/api",
+			changedFiles: []string{"modules/common/main.tf"},
+			wantStatus:   WhyAffected,
+			wantPaths:    []WhyStatus{WhyAffected},
+			wantChains: []string{
+				"environments/stg/api -> module.auth (environments/stg/api/main.tf:11) -> modules/auth -> module.common (modules/auth/main.tf:1) -> modules/common",
+				"environments/stg/api -> module.database (environments/stg/api/main.tf:6) -> modules/database -> module.common (modules/database/main.tf:5) -> modules/common",
+			},
+		},
+		{
+			name:         "root module change",
+			rootModule:   "environments/dev/api",
+			changedFiles: []string{"environments/dev/api/main.tf"},
+			wantStatus:   WhyAffected,
+			wantPaths:    []WhyStatus{WhyAffected},
+			wantChains:   []string{"environments/dev/api"},
+		},
+		{
+			name:         "no dependency path",
+			rootModule:   "environments/dev/web",
+			changedFiles: []string{"modules/database/main.tf"},
+			wantStatus:   WhyNoDependencyPath,
+			wantPaths:    []WhyStatus{WhyNoDependencyPath},
+		},
+		{
+			name:         "excluded root module",
+			rootModule:   "environments/dev/web",
+			changedFiles: []string{"modules/network/main.tf"},
+			exclude:      []string{"environments/dev/*"},
+			wantStatus:   WhyExcluded,
+			wantPaths:    []WhyStatus{WhyAffected},
+			wantChains:   []string{"environments/dev/web -> module.network (environments/dev/web/main.tf:1) -> modules/network"},
+		},
+		{
+			name:         "pattern mismatch",
+			rootModule:   "modules/database",
+			changedFiles: []string{"modules/common/main.tf"},
+			wantStatus:   WhyPatternMismatch,
+			wantPaths:    []WhyStatus{WhyAffected},
+			wantChains:   []string{"modules/database -> module.common (modules/database/main.tf:5) -> modules/common"},
+		},
+		{
+			name:         "paths outside root and without module",
+			rootModule:   "environments/dev/api",
+			changedFiles: []string{"../terraform-targets/modules/app/main.tf", "environments/README.md"},
+			wantStatus:   WhyNoDependencyPath,
+			wantPaths:    []WhyStatus{WhyOutsideRoot, WhyNoModule},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Root:                  testRoot,
+				RootModulePatterns:    []string{"environments/*/*"},
+				ExcludeModulePatterns: tt.exclude,
+				ChangedFiles:          tt.changedFiles,
+			}
+			e, err := Why(t.Context(), cfg, nil, tt.rootModule)
+			if err != nil {
+				t.Fatalf("Why() error = %v", err)
+			}
+
+			if e.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", e.Status, tt.wantStatus)
+			}
+			var gotPaths []WhyStatus
+			var gotChains []string
+			for _, p := range e.Paths {
+				gotPaths = append(gotPaths, p.Status)
+				for _, c := range p.Chains {
+					gotChains = append(gotChains, c.String())
+				}
+			}
+			if !reflect.DeepEqual(gotPaths, tt.wantPaths) {
+				t.Errorf("path statuses = %v, want %v", gotPaths, tt.wantPaths)
+			}
+			if !reflect.DeepEqual(gotChains, tt.wantChains) {
+				t.Errorf("chains = %q, want %q", gotChains, tt.wantChains)
+			}
+		})
+	}
+}
+
+func TestWhy_AgreesWithRun(t *testing.T) {
+	testdata := filepath.Join("..", "..", "testdata")
+
+	tests := []struct {
+		name        string
+		cfg         Config
+		rootModules []string
+		wantSources map[string][]Cause
+	}{
+		{
+			name: "var file of a target",
+			cfg: Config{
+				Root:               filepath.Join(testdata, "terraform-targets"),
+				RootModulePatterns: []string{"services/*"},
+				ChangedFiles:       []string{"services/api/envs/prod.tfvars"},
+				Targets:            []TargetRule{{RootModulePattern: "services/api", VarFiles: []string{"envs/*.tfvars"}}},
+			},
+			rootModules: []string{"services/api", "services/web"},
+			wantSources: map[string][]Cause{
+				"services/api/envs/prod.tfvars": {{Path: "services/api", Reason: ReasonVarFile}},
+			},
+		},
+		{
+			name: "treat-as-affected parse error policy",
+			cfg: Config{
+				Root:               filepath.Join(testdata, "terraform-parse-errors"),
+				RootModulePatterns: []string{"environments/*"},
+				ChangedFiles:       []string{"environments/ok/main.tf"},
+				OnParseError:       ParseErrorTreatAsAffected,
+			},
+			rootModules: []string{"environments/broken", "environments/ok"},
+			wantSources: map[string][]Cause{
+				"environments/broken":     {{Path: "environments/broken", Reason: ReasonParseError}},
+				"environments/ok/main.tf": {{Path: "environments/ok", Reason: ReasonModule}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Run(t.Context(), tt.cfg, nil)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			affected := make(map[string]bool)
+			for _, m := range result.AffectedModules {
+				affected[m.Path] = true
+			}
+
+			for _, rootModule := range tt.rootModules {
+				e, err := Why(t.Context(), tt.cfg, nil, rootModule)
+				if err != nil {
+					t.Fatalf("Why(%s) error = %v", rootModule, err)
+				}
+				if e.Affected() != affected[rootModule] {
+					t.Errorf("Why(%s).Affected() = %v, Run affected = %v", rootModule, e.Affected(), affected[rootModule])
+				}
+				gotSources := make(map[string][]Cause)
+				for _, p := range e.Paths {
+					gotSources[p.Path] = p.Sources
+				}
+				if !reflect.DeepEqual(gotSources, tt.wantSources) {
+					t.Errorf("Why(%s) sources = %+v, want %+v", rootModule, gotSources, tt.wantSources)
+				}
+			}
+		})
+	}
+}
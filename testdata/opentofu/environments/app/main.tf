@@ -0,0 +1,4 @@
+# Shadowed by main.tofu in OpenTofu mode
+module "storage" {
+  source = "../../modules/storage"
+}
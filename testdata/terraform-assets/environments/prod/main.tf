@@ -0,0 +1,22 @@
+variable "environment" {
+  type    = string
+  default = "prod"
+}
+
+module "lambda" {
+  source = "../../modules/lambda"
+  name   = "prod"
+}
+
+resource "aws_instance" "bastion" {
+  ami       = "ami-12345"
+  user_data = templatefile("${path.root}/templates/user_data.tpl", {})
+}
+
+resource "aws_s3_object" "scripts" {
+  for_each = fileset("${path.module}/../../scripts", "*.sh")
+  bucket   = "prod-scripts"
+  key      = each.value
+  source   = "${path.module}/../../scripts/${each.value}"
+  etag     = filemd5("${path.module}/../../scripts/${var.environment}/${each.value}")
+}
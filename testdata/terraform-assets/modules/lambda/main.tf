@@ -0,0 +1,19 @@
+variable "name" {
+  type = string
+}
+
+data "archive_file" "handler" {
+  type        = "zip"
+  source_dir  = "${path.module}/src/handler"
+  output_path = "${path.module}/handler.zip"
+}
+
+resource "aws_iam_policy" "lambda" {
+  name   = var.name
+  policy = file("${path.module}/../../policies/lambda.json")
+}
+
+locals {
+  config = jsondecode(file("${path.root}/config/lambda.json"))
+  tags   = jsondecode(file("config/tags.json"))
+}
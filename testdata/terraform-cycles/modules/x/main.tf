@@ -0,0 +1,7 @@
+module "y" {
+  source = "../y"
+}
+
+module "z" {
+  source = "../z"
+}
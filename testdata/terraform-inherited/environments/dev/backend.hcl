@@ -0,0 +1,2 @@
+bucket = "tfstate-dev"
+region = "ap-northeast-1"
@@ -0,0 +1,8 @@
+terraform {
+  backend "s3" {}
+}
+
+module "network" {
+  source = "../../../modules/network"
+  cidr   = "10.0.0.0/16"
+}
@@ -0,0 +1,2 @@
+bucket = "tfstate-prod"
+region = "ap-northeast-1"
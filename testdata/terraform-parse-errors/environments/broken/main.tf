@@ -0,0 +1,13 @@
+module "network" {
+  source = "../../modules/network"
+  cidr   = "10.0.0.0/16"
+}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+  # Missing closing brace
+
+module "storage" {
+  source  = "../../modules/storage"
+  bucket  = "data"
+}
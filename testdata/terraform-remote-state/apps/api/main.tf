@@ -0,0 +1,23 @@
+data "terraform_remote_state" "web" {
+  backend = "s3"
+  config = {
+    bucket = "example-tfstate"
+    key    = "apps/web.tfstate"
+    region = "ap-northeast-1"
+  }
+}
+
+data "terraform_remote_state" "dns" {
+  backend = "local"
+  config = {
+    path = "../../state/dns.tfstate"
+  }
+}
+
+terraform {
+  backend "s3" {
+    bucket = "example-tfstate"
+    key    = "apps/api.tfstate"
+    region = "ap-northeast-1"
+  }
+}
@@ -0,0 +1,23 @@
+variable "state_key" {
+  type    = string
+  default = "shared/vpc.tfstate"
+}
+
+# Key is only known at plan time and cannot be matched statically
+data "terraform_remote_state" "vpc" {
+  backend = "s3"
+  config = {
+    bucket = "example-tfstate"
+    key    = var.state_key
+  }
+}
+
+terraform {
+  cloud {
+    organization = "example"
+
+    workspaces {
+      name = "batch"
+    }
+  }
+}
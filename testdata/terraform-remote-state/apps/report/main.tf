@@ -0,0 +1,9 @@
+data "terraform_remote_state" "batch" {
+  backend = "remote"
+  config = {
+    organization = "example"
+    workspaces = {
+      name = "batch"
+    }
+  }
+}
@@ -0,0 +1,20 @@
+data "terraform_remote_state" "vpc" {
+  backend = "s3"
+  config = {
+    bucket = "example-tfstate"
+    key    = "shared/vpc.tfstate"
+    region = "ap-northeast-1"
+  }
+}
+
+output "vpc_id" {
+  value = data.terraform_remote_state.vpc.outputs.vpc_id
+}
+
+terraform {
+  backend "s3" {
+    bucket = "example-tfstate"
+    key    = "apps/web.tfstate"
+    region = "ap-northeast-1"
+  }
+}
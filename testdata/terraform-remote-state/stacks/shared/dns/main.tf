@@ -0,0 +1,9 @@
+output "zone_id" {
+  value = "Z12345"
+}
+
+terraform {
+  backend "local" {
+    path = "../../../state/dns.tfstate"
+  }
+}
@@ -0,0 +1,16 @@
+module "network" {
+  source = "../../../modules/network"
+  cidr   = "10.0.0.0/16"
+}
+
+output "vpc_id" {
+  value = module.network.vpc_id
+}
+
+terraform {
+  backend "s3" {
+    bucket = "example-tfstate"
+    key    = "shared/vpc.tfstate"
+    region = "ap-northeast-1"
+  }
+}
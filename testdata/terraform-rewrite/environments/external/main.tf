@@ -0,0 +1,9 @@
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.0.0"
+}
+
+module "network" {
+  source = "git::https://github.com/other-org/infra.git//modules/network?ref=main"
+  name   = "external"
+}
@@ -0,0 +1,5 @@
+module "storage" {
+  source  = "app.terraform.io/our-org/storage/aws"
+  version = "1.2.0"
+  name    = "pinned"
+}
@@ -0,0 +1,4 @@
+module "network" {
+  source = "git::https://github.com/our-org/infra.git//modules/network?ref=v1.4.0"
+  name   = "tagged"
+}
@@ -0,0 +1,8 @@
+variable "environment" {
+  type = string
+}
+
+module "app" {
+  source      = "../../modules/app"
+  environment = var.environment
+}
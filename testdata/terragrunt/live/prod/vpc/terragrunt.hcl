@@ -0,0 +1,15 @@
+include "root" {
+  path = find_in_parent_folders("root.hcl")
+}
+
+locals {
+  env = read_terragrunt_config(find_in_parent_folders("env.hcl"))
+}
+
+terraform {
+  source = "../../../modules//vpc"
+}
+
+inputs = {
+  cidr = "10.0.0.0/16"
+}
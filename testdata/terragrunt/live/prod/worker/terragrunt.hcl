@@ -0,0 +1,12 @@
+include "root" {
+  path = find_in_parent_folders("root.hcl")
+}
+
+# Remote module - should be ignored
+terraform {
+  source = "git::https://github.com/example/modules.git//worker?ref=v1.0.0"
+}
+
+dependencies {
+  paths = ["../app"]
+}
@@ -0,0 +1,8 @@
+remote_state {
+  backend = "s3"
+  config = {
+    bucket = "example-tfstate"
+    key    = "${path_relative_to_include()}/terraform.tfstate"
+    region = "ap-northeast-1"
+  }
+}